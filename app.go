@@ -1,11 +1,18 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
 )
 
 // Focus indicates which pane has keyboard focus
@@ -16,6 +23,47 @@ const (
 	FocusViewer
 )
 
+func (f Focus) String() string {
+	if f == FocusViewer {
+		return "viewer"
+	}
+	return "nav"
+}
+
+// parseFocusOrder reads the focus_order config into the ring tab cycles
+// through
+func parseFocusOrder(names []string) ([]Focus, error) {
+	if len(names) == 0 {
+		return nil, errors.New("must name at least one pane")
+	}
+	ring := make([]Focus, 0, len(names))
+	for _, name := range names {
+		var f Focus
+		switch name {
+		case "nav":
+			f = FocusNav
+		case "viewer":
+			f = FocusViewer
+		default:
+			return nil, fmt.Errorf("panes are nav or viewer, not %q", name)
+		}
+		if slices.Contains(ring, f) {
+			return nil, fmt.Errorf("%s is listed twice", name)
+		}
+		ring = append(ring, f)
+	}
+	return ring, nil
+}
+
+// Panes says which panes are drawn
+type Panes int
+
+const (
+	PanesBoth   Panes = iota
+	PanesNav          // the viewer is hidden, or the nav zoomed
+	PanesViewer       // the viewer is zoomed
+)
+
 // Mode indicates the current application mode
 type Mode int
 
@@ -33,6 +81,37 @@ type Pane interface {
 	SetFocused(focused bool)
 }
 
+// inputCapturer is implemented by panes that can be reading a prompt
+// answer, during which every key must reach them
+type inputCapturer interface {
+	CapturingInput() bool
+}
+
+// keyClaimer is implemented by panes that sometimes want a key the App
+// otherwise handles globally
+type keyClaimer interface {
+	ClaimsKey(key string) bool
+}
+
+// editMarker is implemented by panes that mark the file open in the
+// editor
+type editMarker interface {
+	SetEditing(path string) // "" when nothing is being edited
+}
+
+// goToTopTimeout is how long after a first g in gg mode the second one
+// still goes to the top
+const goToTopTimeout = time.Second
+
+// Options holds per-invocation settings from the command line
+type Options struct {
+	PickDir  bool   // run as a directory picker
+	Path     string // file or directory to open at startup
+	Line     int    // 1-based line to show Path at, 0 for the top
+	Col      int    // 1-based column for the editor, 0 for the start
+	Headless bool   // rendering without a terminal: saved state is neither read nor written
+}
+
 // App is the main application model that orchestrates panes
 type App struct {
 	width  int
@@ -41,43 +120,200 @@ type App struct {
 
 	focus Focus
 	mode  Mode
+	cfg   Config
 
 	nav        Pane
 	viewer     *ViewerRouter
 	editor     *Editor
 	editPath   string // path being edited
+	editNotice string // warning shown when editPath is opened in the editor
+	editLine   int    // line and column to open editPath at, from the command line
+	editCol    int
+	chosenPath string // directory confirmed in picker mode
+	startCmd   tea.Cmd
+	confirm    *ConfirmMsg  // question awaiting y/n
+	prompt     *inputPrompt // line of text being entered
+	flash      string       // note over the bottom line until the next key
+	observers  []func(AppEvent)
+	focusRing  []Focus   // panes tab moves through, in order
+	pendingG   time.Time // when a first g of gg was pressed
+	stdout     []byte    // exported content printed on quit
+
+	panes Panes // one pane can take the full width
+
+	navRatio    float64 // nav pane share of the width
+	navRoot     string  // nav root last seen, to notice re-rooting
+	layoutDir   string  // directory the current layout is remembered for
+	layouts     map[string]paneLayout
+	layoutsPath string
 }
 
-// NavPane ratio (left side width percentage)
+// Default nav pane ratio (left side width percentage)
 const navPaneRatio = 0.25
 
-func NewApp() *App {
+func NewApp(cfg Config, opts Options) *App {
 	cwd, _ := os.Getwd()
 	cwd, _ = filepath.EvalSymlinks(cwd) // resolve to real path
 
-	nav := NewNavPane("/")
-	nav.ExpandToPath(cwd)
-	nav.PinTop() // keep root visible
+	// A file given on the command line opens straight away; a directory
+	// takes the place of cwd
+	start, reveal := cwd, cwd
+	var open tea.Cmd
+	if opts.Path != "" {
+		path, _ := filepath.Abs(opts.Path)
+		if resolved, err := filepath.EvalSymlinks(path); err == nil {
+			path = resolved
+		}
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			start, reveal = filepath.Dir(path), path
+			open = func() tea.Msg {
+				return FileSelectedMsg{Path: path, Line: opts.Line, Col: opts.Col}
+			}
+		} else if err == nil {
+			start, reveal = path, path
+		}
+	}
+
+	var nav *NavPane
+	var startCmd tea.Cmd
+	if opts.PickDir {
+		// Picker starts in cwd so it can be confirmed straight away
+		nav = NewNavPane(start)
+		nav.SetDirsOnly(true)
+		nav.SetBadges(cfg.Nav.Badges, cfg.Nav.BadgeColor)
+	} else {
+		nav = NewNavPane("/")
+		nav.SetBadges(cfg.Nav.Badges, cfg.Nav.BadgeColor) // before the first reads
+		levels, _ := parseStartupExpand(cfg.Nav.StartupExpand)
+		if opts.Path != "" || levels == expandToCwd {
+			startCmd = nav.ExpandToPath(reveal)
+		} else {
+			nav.ExpandLevels(levels)
+		}
+		nav.PinTop() // keep root visible
+	}
+	nav.SetHide(cfg.Nav.Hide)
+	nav.SetExactSizes(cfg.Format.Sizes == "bytes")
+	nav.SetScrollOff(cfg.ScrollOff)
+	nav.SetCursorStyle(newCursorStyle(cfg.Cursor))
+	nav.SetScrollbar(newScrollbarStyle(cfg.Scrollbar))
+	nav.SetSortOrder(newSortOrder(cfg.Sort))
+	nav.SetWrapAround(cfg.WrapAround)
+	nav.SetLeftCollapses(cfg.Nav.Left == "collapse")
+	nav.SetEnterDescends(cfg.Nav.Enter == "descend")
+	nav.SetFocusOnOpen(cfg.Nav.FocusOnOpen)
+	truncate, _ := parseTruncation(cfg.Nav.Truncate)
+	nav.SetTruncation(truncate)
+	caseMode, _ := parseCaseMode(cfg.Search.Case)
+	nav.SetCaseMode(caseMode)
 	nav.SetFocused(true)
+	templateDir := cfg.Editor.Templates
+	if templateDir == "" {
+		templateDir, _ = dataPath("templates")
+	}
+	nav.SetTemplateDir(templateDir)
+
+	var favoritesPath, layoutsPath, jsonViewsPath string
+	if !opts.Headless {
+		favoritesPath, _ = dataPath("favorites.json")
+		layoutsPath, _ = dataPath("layouts.json")
+		jsonViewsPath, _ = dataPath("json-views.json")
+	}
+	var favorites []string
+	if favoritesPath != "" {
+		loadJSON(favoritesPath, &favorites) // unreadable favorites start empty
+	}
+	nav.SetFavorites(favorites, favoritesPath)
+
+	focusRing, err := parseFocusOrder(cfg.FocusOrder)
+	if err != nil {
+		focusRing = []Focus{FocusNav, FocusViewer} // LoadConfig rejects this
+	}
+
+	a := &App{
+		focusRing: focusRing,
+		focus:     FocusNav,
+		mode:      ModeNav,
+		cfg:       cfg,
+		nav:       nav,
+		viewer:    NewViewerRouter(cfg),
+		editor:    NewEditor(cfg.Editor),
+		startCmd:  tea.Batch(startCmd, open),
+		navRatio:  navPaneRatio,
+		navRoot:   nav.Root(),
+	}
 
-	return &App{
-		focus:  FocusNav,
-		mode:   ModeNav,
-		nav:    nav,
-		viewer: NewViewerRouter(),
-		editor: NewEditor(),
+	// Layouts are remembered for the starting directory until the tree is
+	// re-rooted somewhere else
+	var layouts map[string]paneLayout
+	if layoutsPath != "" {
+		loadJSON(layoutsPath, &layouts) // unreadable layouts start empty
+	}
+	layoutDir := start
+	if opts.PickDir {
+		layoutDir = nav.Root()
 	}
+	a.SetLayouts(layouts, layoutsPath, layoutDir)
+	a.editor.SetFormatBadge(newFormatBadge(cfg.Encoding))
+
+	var jsonViews map[string]savedJSONView
+	if jsonViewsPath != "" {
+		loadJSON(jsonViewsPath, &jsonViews) // unreadable views start empty
+	}
+	a.viewer.SetSavedJSONViews(jsonViews, jsonViewsPath)
+	return a
 }
 
 func (a *App) Init() tea.Cmd {
-	return nil
+	return tea.Batch(a.startCmd, a.nav.Init())
 }
 
 func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	focus, mode, editing := a.focus, a.mode, a.editor.path
+	m, cmd := a.update(msg)
+	if a.mode != mode || a.editor.path != editing {
+		a.markEditing()
+	}
+	a.notify(msg, focus, mode)
+	return m, cmd
+}
+
+// markEditing tells the nav and the viewer which file the editor has
+// open, for them to mark where they show it
+func (a *App) markEditing() {
+	path := ""
+	if a.mode == ModeEditor {
+		path = a.editor.path
+	}
+	a.viewer.SetEditing(path)
+	if m, ok := a.nav.(editMarker); ok {
+		m.SetEditing(path)
+	}
+}
+
+func (a *App) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmds []tea.Cmd
 
 	switch msg := msg.(type) {
+	case ConfirmMsg:
+		if !a.cfg.Confirms(msg.Action) {
+			return a, msg.Yes()
+		}
+		a.confirm = &msg
+
+	case PromptMsg:
+		return a, a.openPrompt(msg)
+
 	case tea.KeyMsg:
+		a.flash = ""
+		// An open question takes every key until it is answered
+		if a.confirm != nil {
+			return a, a.updateConfirm(msg)
+		}
+		if a.prompt != nil {
+			return a, a.updatePrompt(msg)
+		}
+
 		// In editor mode, only editor handles keys (except ctrl+c for emergency exit)
 		if a.mode == ModeEditor {
 			if msg.String() == "ctrl+c" {
@@ -93,28 +329,67 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return a, tea.Batch(cmds...)
 		}
 
+		// A pane reading a prompt answer gets keys before global bindings
+		if a.capturingInput() {
+			if msg.String() == "ctrl+c" {
+				return a, tea.Quit
+			}
+			return a, a.updateFocusedPane(msg)
+		}
+
 		switch msg.String() {
 		case "q", "ctrl+c":
+			if msg.String() == "q" && a.focus == FocusViewer && a.viewer.ClaimsKey("q") {
+				break
+			}
 			return a, tea.Quit
 
 		case "tab":
-			a.cycleFocus()
+			a.cycleFocus(1)
+			a.saveLayout()
+			return a, nil
+
+		case "shift+tab":
+			a.cycleFocus(-1)
+			a.saveLayout()
 			return a, nil
 
+		case "P":
+			return a, a.toggleViewer()
+
+		case "F":
+			return a, a.toggleZoom()
+
+		case "<":
+			if a.panes == PanesBoth {
+				a.resizeNav(-navRatioStep)
+			}
+			return a, nil
+
+		case ">":
+			if a.panes == PanesBoth {
+				a.resizeNav(navRatioStep)
+			}
+			return a, nil
+
+		case "x":
+			if a.focus == FocusViewer && !a.viewer.ClaimsKey("x") {
+				return a, a.export()
+			}
+
 		case "e":
 			// Open editor for current file (if viewing a text file)
-			if a.focus == FocusViewer && a.editPath != "" && isTextFile(a.editPath) {
-				a.mode = ModeEditor
-				a.editor.SetSize(a.width-int(float64(a.width)*navPaneRatio)-1, a.height)
-				a.editor.SetFocused(true)
-				a.viewer.SetFocused(false)
-				cmd := a.editor.Open(a.editPath)
-				if cmd != nil {
-					cmds = append(cmds, cmd)
+			if a.focus == FocusViewer && a.editPath != "" && !isDir(a.editPath) && (isTextFile(a.editPath) || a.cfg.FiletypeFor(a.editPath) != "") && !a.viewer.ClaimsKey("e") {
+				line, col := a.viewer.EditLine(), 0
+				if line == 0 {
+					line, col = a.editLine, a.editCol
 				}
-				return a, tea.Batch(cmds...)
+				return a, a.openEditor(line, col)
 			}
 		}
+		if !a.goToTop(msg) {
+			return a, nil
+		}
 
 		// Forward key events to focused pane
 		cmd := a.updateFocusedPane(msg)
@@ -127,12 +402,116 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		a.height = msg.Height
 		a.ready = true
 		a.updatePaneSizes()
+		a.sizePrompt()
+		if cmd := a.viewer.Rerender(); cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+
+	case DirChosenMsg:
+		a.chosenPath = msg.Path
+		return a, tea.Quit
 
 	case FileSelectedMsg:
+		action := "view" // directories are always listed
+		if !isDir(msg.Path) {
+			action = a.cfg.EnterAction(msg.Path)
+		}
+		if action == "open" {
+			// Handed off whole; the viewer keeps what it shows
+			return a, openExternal(msg.Path)
+		}
 		// Track path for potential editing
 		a.editPath = msg.Path
+		a.editNotice = ""
+		if msg.Outside {
+			a.editNotice = "Editing link target outside the tree: " + msg.Path
+		}
+		a.editLine, a.editCol = msg.Line, msg.Col
+		// Opening a file shows it, even with the viewer hidden
+		if cmd := a.showViewer(); cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+		if msg.Focus && a.mode != ModeEditor {
+			a.setFocus(FocusViewer)
+		}
 		// Open file in viewer
-		cmd := a.viewer.OpenFile(msg.Path)
+		var cmd tea.Cmd
+		if action == "hex" {
+			cmd = a.viewer.OpenHex(msg.Path)
+		} else {
+			cmd = a.viewer.OpenFile(msg.Path, msg.Line)
+		}
+		if cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+		// Extensions configured to edit skip the preview; the viewer
+		// still loads so leaving the editor lands on the file
+		if action == "edit" {
+			if info, err := os.Stat(msg.Path); err == nil && !info.IsDir() {
+				cmds = append(cmds, a.openEditor(msg.Line, msg.Col))
+			}
+		}
+
+	case DirLoadedMsg:
+		// Forward to nav
+		m, cmd := a.nav.Update(msg)
+		a.nav = m.(Pane)
+		if cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+
+	case FinderBatchMsg:
+		// Forward to nav
+		m, cmd := a.nav.Update(msg)
+		a.nav = m.(Pane)
+		if cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+
+	case DirSizeMsg, spinner.TickMsg, TypeAheadIdleMsg:
+		// Forward to nav
+		m, cmd := a.nav.Update(msg)
+		a.nav = m.(Pane)
+		if cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+
+	case FileGoneMsg:
+		// Nothing is left to edit, and the nav lists the file no more
+		if a.editPath == msg.Path {
+			a.editPath = ""
+		}
+		m, cmd := a.nav.Update(msg)
+		a.nav = m.(Pane)
+		if cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+
+	case DirListedMsg:
+		// Forward to viewer
+		_, cmd := a.viewer.Update(msg)
+		if cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+		cmds = append(cmds, fileGone(msg.Path, msg.Err))
+
+	case FileOpDoneMsg:
+		// Forward to nav so it can report and refresh
+		m, cmd := a.nav.Update(msg)
+		a.nav = m.(Pane)
+		if cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+		// A new file goes straight into the editor, template and all
+		if msg.Op == OpCreate && msg.Results[0].Err == nil {
+			path := msg.Results[0].Path
+			a.editPath, a.editNotice = path, ""
+			cmds = append(cmds, a.viewer.OpenFile(path, 0), a.openEditor(0, 0))
+		}
+
+	case TextWindowMsg:
+		// Forward to viewer
+		_, cmd := a.viewer.Update(msg)
 		if cmd != nil {
 			cmds = append(cmds, cmd)
 		}
@@ -143,6 +522,7 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if cmd != nil {
 			cmds = append(cmds, cmd)
 		}
+		cmds = append(cmds, fileGone(msg.Path, msg.Err))
 
 	case MarkdownLoadedMsg:
 		// Forward to viewer
@@ -150,6 +530,22 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if cmd != nil {
 			cmds = append(cmds, cmd)
 		}
+		cmds = append(cmds, fileGone(msg.Path, msg.Err))
+
+	case HexLoadedMsg:
+		// Forward to viewer
+		_, cmd := a.viewer.Update(msg)
+		if cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+		cmds = append(cmds, fileGone(msg.Path, msg.Err))
+
+	case JSONSavedMsg, JSONSpansMsg:
+		// Forward to viewer
+		_, cmd := a.viewer.Update(msg)
+		if cmd != nil {
+			cmds = append(cmds, cmd)
+		}
 
 	case JSONLoadedMsg:
 		// Forward to viewer
@@ -157,6 +553,39 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if cmd != nil {
 			cmds = append(cmds, cmd)
 		}
+		cmds = append(cmds, fileGone(msg.Path, msg.Err))
+
+	case BlameLoadedMsg:
+		// Forward to viewer
+		_, cmd := a.viewer.Update(msg)
+		if cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+
+	case ClipboardMsg:
+		// Forward to viewer
+		_, cmd := a.viewer.Update(msg)
+		if cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+
+	case ExportedMsg:
+		if msg.Err == nil && msg.Path == exportStdout {
+			a.stdout = msg.Data
+		}
+		a.flash = msg.Status()
+
+	case ExternalOpenedMsg:
+		// Forward to the pane that asked, which still has focus
+		if a.focus == FocusNav {
+			m, cmd := a.nav.Update(msg)
+			a.nav = m.(Pane)
+			if cmd != nil {
+				cmds = append(cmds, cmd)
+			}
+		} else if _, cmd := a.viewer.Update(msg); cmd != nil {
+			cmds = append(cmds, cmd)
+		}
 
 	case EditorOpenMsg:
 		// Forward to editor
@@ -165,18 +594,37 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			cmds = append(cmds, cmd)
 		}
 
+	case EditorIdleMsg:
+		// Forward to editor
+		_, cmd := a.editor.Update(msg)
+		if cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+
 	case EditorSavedMsg:
+		// The editor keeps the buffer, and says why, when the save failed
+		a.editor.Update(msg)
+		if msg.Err != nil {
+			return a, nil
+		}
+		if msg.Kind == saveIdle {
+			// Keep editing; the viewer underneath catches up
+			return a, a.viewer.OpenFile(msg.Path, 0)
+		}
 		// Return to viewer mode and refresh
 		a.mode = ModeViewer
 		a.editor.SetFocused(false)
 		a.viewer.SetFocused(true)
 		a.focus = FocusViewer
+		verb := "Saved"
+		if msg.Kind == saveOnLeave {
+			verb = "Auto-saved"
+		}
+		a.flash = fmt.Sprintf("%s %s to %s", verb, plural(msg.Bytes, "byte"), msg.Path)
 		// Reload file in viewer to show changes
-		if msg.Err == nil {
-			cmd := a.viewer.OpenFile(msg.Path)
-			if cmd != nil {
-				cmds = append(cmds, cmd)
-			}
+		cmd := a.viewer.OpenFile(msg.Path, 0)
+		if cmd != nil {
+			cmds = append(cmds, cmd)
 		}
 
 	case EditorCancelledMsg:
@@ -195,58 +643,155 @@ func (a *App) View() string {
 		return "Initializing..."
 	}
 
-	navWidth := int(float64(a.width) * navPaneRatio)
-	rightWidth := a.width - navWidth - 1 // -1 for border
-
-	navStyle := lipgloss.NewStyle().
-		Width(navWidth).
-		Height(a.height).
-		AlignVertical(lipgloss.Top).
-		BorderStyle(lipgloss.NormalBorder()).
-		BorderRight(true)
-
-	rightStyle := lipgloss.NewStyle().
-		Width(rightWidth).
-		Height(a.height).
-		AlignVertical(lipgloss.Top)
-
-	if a.focus == FocusNav {
-		navStyle = navStyle.BorderForeground(lipgloss.Color("62"))
-	} else {
-		rightStyle = rightStyle.BorderForeground(lipgloss.Color("62"))
-	}
-
 	// Show editor or viewer depending on mode
 	var rightPane string
-	if a.mode == ModeEditor {
+	var navPane string
+	if a.panes != PanesViewer {
+		navPane = a.nav.View()
+	}
+	switch {
+	case a.panes == PanesNav:
+	case a.mode == ModeEditor:
 		rightPane = a.editor.View()
-	} else {
+	default:
 		rightPane = a.viewer.View()
 	}
 
-	return lipgloss.JoinHorizontal(
-		lipgloss.Top,
-		navStyle.Render(a.nav.View()),
-		rightStyle.Render(rightPane),
-	)
+	frame := a.renderPanes(navPane, rightPane)
+	switch {
+	case a.confirm != nil:
+		frame = a.overlayConfirm(frame)
+	case a.prompt != nil:
+		frame = a.overlayPrompt(frame)
+	case a.flash != "":
+		frame = a.overlayFlash(frame)
+	}
+	return frame
 }
 
-func (a *App) cycleFocus() {
-	if a.focus == FocusNav {
-		a.focus = FocusViewer
-	} else {
-		a.focus = FocusNav
+// overlayFlash puts the flash note over the last line of the frame
+func (a *App) overlayFlash(frame string) string {
+	lines := strings.Split(frame, "\n")
+	lines[len(lines)-1] = lipgloss.NewStyle().
+		Foreground(lipgloss.Color("114")).
+		Width(a.width).
+		Render(ansi.Truncate(a.flash, a.width, "…"))
+	return strings.Join(lines, "\n")
+}
+
+// Close saves what is only written on exit, once the program has ended
+func (a *App) Close() {
+	a.viewer.Close()
+}
+
+// ChosenPath returns the directory confirmed in picker mode, if any
+func (a *App) ChosenPath() string {
+	return a.chosenPath
+}
+
+// Stdout returns the content exported to stdout, printed once the
+// terminal is given back
+func (a *App) Stdout() []byte {
+	return a.stdout
+}
+
+// isDir reports whether path is a directory, following symlinks
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// openEditor switches to the editor on editPath at a 1-based line and
+// column, 0 for the start. Changes not yet saved in the editor would be
+// read over, so it asks first.
+func (a *App) openEditor(line, col int) tea.Cmd {
+	if a.mode == ModeEditor && a.editor.modified {
+		path := a.editPath
+		question := "Discard unsaved changes to " + filepath.Base(a.editor.path) + " and open " + filepath.Base(path) + "?"
+		return askConfirm("discard", question, func() tea.Cmd {
+			a.editPath = path
+			return a.startEditor(line, col)
+		}, nil)
 	}
+	return a.startEditor(line, col)
+}
+
+// startEditor opens editPath in the editor, whatever it holds
+func (a *App) startEditor(line, col int) tea.Cmd {
+	show := a.showViewer()
+	a.mode = ModeEditor
+	a.focus = FocusViewer
+	_, rightWidth, height := a.paneSizes()
+	a.editor.SetSize(rightWidth, height)
+	a.editor.SetFocused(true)
+	a.nav.SetFocused(false)
+	a.viewer.SetFocused(false)
+	a.editor.SetNotice(a.editNotice)
+	return tea.Batch(show, a.editor.Open(a.editPath, line, col))
+}
+
+// cycleFocus moves focus delta steps around the focus ring, or to its
+// first pane when the focused one is not in it
+func (a *App) cycleFocus(delta int) {
+	i := slices.Index(a.focusRing, a.focus)
+	if i < 0 {
+		a.setFocus(a.focusRing[0])
+		return
+	}
+	n := len(a.focusRing)
+	a.setFocus(a.focusRing[((i+delta)%n+n)%n])
+}
+
+// setFocus focuses the nav or the viewer; a pane that is not drawn is
+// never focused
+func (a *App) setFocus(f Focus) {
+	switch a.panes {
+	case PanesNav:
+		f = FocusNav
+	case PanesViewer:
+		f = FocusViewer
+	}
+	a.focus = f
 	a.nav.SetFocused(a.focus == FocusNav)
 	a.viewer.SetFocused(a.focus == FocusViewer)
 }
 
+// goToTop holds back a lone g when go_to_top is gg, reporting whether the
+// key should reach the focused pane: the second g of a pair does, as the
+// g the panes take for the top
+func (a *App) goToTop(msg tea.KeyMsg) bool {
+	if a.cfg.GoToTop != "gg" {
+		return true
+	}
+	pending := a.pendingG
+	a.pendingG = time.Time{}
+	if msg.String() != "g" {
+		return true
+	}
+	if !pending.IsZero() && time.Since(pending) < goToTopTimeout {
+		return true
+	}
+	a.pendingG = time.Now()
+	return false
+}
+
+// capturingInput reports whether the focused pane is reading a prompt
+func (a *App) capturingInput() bool {
+	var p Pane = a.viewer
+	if a.focus == FocusNav {
+		p = a.nav
+	}
+	c, ok := p.(inputCapturer)
+	return ok && c.CapturingInput()
+}
+
 func (a *App) updateFocusedPane(msg tea.Msg) tea.Cmd {
 	var cmd tea.Cmd
 	if a.focus == FocusNav {
 		var m tea.Model
 		m, cmd = a.nav.Update(msg)
 		a.nav = m.(Pane)
+		a.followRoot()
 	} else {
 		var m tea.Model
 		m, cmd = a.viewer.Update(msg)
@@ -256,10 +801,13 @@ func (a *App) updateFocusedPane(msg tea.Msg) tea.Cmd {
 }
 
 func (a *App) updatePaneSizes() {
-	navWidth := int(float64(a.width) * navPaneRatio)
-	rightWidth := a.width - navWidth - 1
-
-	a.nav.SetSize(navWidth, a.height)
-	a.viewer.SetSize(rightWidth, a.height)
-	a.editor.SetSize(rightWidth, a.height)
+	// A pane not drawn keeps its size for when it is shown again
+	navWidth, rightWidth, height := a.paneSizes()
+	if a.panes != PanesViewer {
+		a.nav.SetSize(navWidth, height)
+	}
+	if a.panes != PanesNav {
+		a.viewer.SetSize(rightWidth, height)
+		a.editor.SetSize(rightWidth, height)
+	}
 }
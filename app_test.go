@@ -0,0 +1,66 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestOpenFileDeletedAfterListing removes a file after the nav has listed
+// it, then selects it: the viewer must explain it no longer exists, and
+// the nav drop it, without panicking
+func TestOpenFileDeletedAfterListing(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	app := NewApp(DefaultConfig(), Options{Path: dir, Headless: true})
+	if frame := renderFrame(app, 80, 12, nil); !strings.Contains(frame, "b.txt") {
+		t.Fatalf("b.txt not listed:\n%s", frame)
+	}
+
+	if err := os.Remove(filepath.Join(dir, "b.txt")); err != nil {
+		t.Fatal(err)
+	}
+	frame := renderFrame(app, 80, 12, parseKeys("j j enter"))
+	if !strings.Contains(frame, "No longer exists") {
+		t.Errorf("no error view for the deleted file:\n%s", frame)
+	}
+	for _, entry := range app.nav.(*NavPane).entries {
+		if entry.Name == "b.txt" {
+			t.Errorf("the deleted file is still listed:\n%s", frame)
+		}
+	}
+}
+
+// TestOpenOtherFileWhileEditing opens another file for editing while the
+// editor holds unsaved changes, which must ask before reading over them,
+// with the nav still marking the file being edited
+func TestOpenOtherFileWhileEditing(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg := DefaultConfig()
+	cfg.Enter[".txt"] = "edit"
+	app := NewApp(cfg, Options{Path: dir, Headless: true})
+	a := filepath.Join(dir, "a.txt")
+	renderFrame(app, 80, 12, parseKeys("j enter x"))
+	if app.mode != ModeEditor || app.editor.path != a || !app.editor.modified {
+		t.Fatalf("a.txt not being edited:\n%s", app.View())
+	}
+
+	_, cmd := app.Update(FileSelectedMsg{Path: filepath.Join(dir, "b.txt")})
+	settle(app, cmd)
+	if app.confirm == nil {
+		t.Errorf("no question before the changes to a.txt were read over")
+	}
+	if app.editor.path != a || app.nav.(*NavPane).editing != a {
+		t.Errorf("editing %q, nav marks %q, want a.txt for both", app.editor.path, app.nav.(*NavPane).editing)
+	}
+}
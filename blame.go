@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// blameWidth is the width of the blame gutter: short commit, author and
+// date, each followed by a space
+const blameWidth = 8 + 1 + 12 + 1 + 10 + 1
+
+// blameLine is who last changed one line of a file
+type blameLine struct {
+	Commit string
+	Author string
+	Date   time.Time
+}
+
+// BlameLoadedMsg is sent when git blame finishes for a file
+type BlameLoadedMsg struct {
+	Path    string
+	ModTime time.Time   // of the file when blamed
+	Lines   []blameLine // indexed by 0-based line number
+	Err     error
+}
+
+// loadBlame runs git blame on path from its own directory, so any
+// repository it belongs to is found
+func loadBlame(path string) tea.Cmd {
+	return func() tea.Msg {
+		info, err := os.Stat(path)
+		if err != nil {
+			return BlameLoadedMsg{Path: path, Err: err}
+		}
+		modTime := info.ModTime()
+		cmd := exec.Command("git", "blame", "--porcelain", "--", filepath.Base(path))
+		cmd.Dir = filepath.Dir(path)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				err = fmt.Errorf("%s", firstLine(msg))
+			}
+			return BlameLoadedMsg{Path: path, ModTime: modTime, Err: err}
+		}
+		return BlameLoadedMsg{Path: path, ModTime: modTime, Lines: parseBlame(out)}
+	}
+}
+
+// parseBlame reads git blame --porcelain output. Commit details are only
+// given the first time a commit appears, so they are remembered by hash.
+func parseBlame(out []byte) []blameLine {
+	var lines []blameLine
+	commits := make(map[string]*blameLine)
+	var current *blameLine
+	line := 0
+
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		text := sc.Text()
+		if strings.HasPrefix(text, "\t") {
+			// The line's content ends its entry
+			for len(lines) < line {
+				lines = append(lines, blameLine{})
+			}
+			if current != nil && line > 0 {
+				lines[line-1] = *current
+			}
+			current = nil
+			continue
+		}
+		key, value, _ := strings.Cut(text, " ")
+		if current == nil {
+			// Entry header: <hash> <orig line> <final line> [<count>]
+			fields := strings.Fields(value)
+			if len(fields) < 2 {
+				continue
+			}
+			line, _ = strconv.Atoi(fields[1])
+			c, ok := commits[key]
+			if !ok {
+				c = &blameLine{Commit: key}
+				commits[key] = c
+			}
+			current = c
+			continue
+		}
+		switch key {
+		case "author":
+			current.Author = value
+		case "author-time":
+			if sec, err := strconv.ParseInt(value, 10, 64); err == nil {
+				current.Date = time.Unix(sec, 0)
+			}
+		}
+	}
+	return lines
+}
+
+// render formats the gutter for one line
+func (b blameLine) render() string {
+	if b.Commit == "" {
+		return strings.Repeat(" ", blameWidth)
+	}
+	commit := b.Commit[:min(8, len(b.Commit))]
+	author := b.Author
+	if strings.Trim(b.Commit, "0") == "" {
+		commit, author = "--------", "uncommitted"
+	}
+	author = ansi.Truncate(author, 12, "…")
+	author += strings.Repeat(" ", 12-ansi.StringWidth(author))
+	return commit + " " + author + " " + b.Date.Format("2006-01-02") + " "
+}
+
+// firstLine returns s up to its first newline
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
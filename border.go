@@ -0,0 +1,106 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// boxBorders are the border styles that draw a box around each pane
+var boxBorders = map[string]lipgloss.Border{
+	"normal":  lipgloss.NormalBorder(),
+	"rounded": lipgloss.RoundedBorder(),
+	"thick":   lipgloss.ThickBorder(),
+	"double":  lipgloss.DoubleBorder(),
+}
+
+// paneSizes returns the room left for the nav and right panes' content
+// once the configured borders are drawn
+func (a *App) paneSizes() (navWidth, rightWidth, height int) {
+	if a.panes != PanesBoth {
+		width, height := a.width, a.height
+		if _, box := boxBorders[a.cfg.Border.Style]; box {
+			width -= 2
+			height -= 2
+		}
+		if a.panes == PanesNav {
+			return max(0, width), 0, max(0, height)
+		}
+		return 0, max(0, width), max(0, height)
+	}
+	navWidth = a.navWidth()
+	rightWidth = a.width - navWidth
+	height = a.height
+	switch a.cfg.Border.Style {
+	case "line":
+		rightWidth-- // the divider
+	case "none":
+	default:
+		navWidth -= 2
+		rightWidth -= 2
+		height -= 2
+	}
+	return max(0, navWidth), max(0, rightWidth), max(0, height)
+}
+
+// renderPanes lays the panes side by side in their borders. Boxes take
+// the focus color around the focused pane; the line divider points at it.
+func (a *App) renderPanes(nav, right string) string {
+	navWidth, rightWidth, height := a.paneSizes()
+	navStyle := lipgloss.NewStyle().
+		Width(navWidth).
+		Height(height).
+		AlignVertical(lipgloss.Top)
+	rightStyle := navStyle.Width(rightWidth)
+
+	focusColor := lipgloss.Color(a.cfg.Border.FocusColor)
+	plain := lipgloss.NewStyle()
+	if a.cfg.Border.Color != "" {
+		plain = plain.Foreground(lipgloss.Color(a.cfg.Border.Color))
+	}
+
+	if box, ok := boxBorders[a.cfg.Border.Style]; ok {
+		navStyle = navStyle.Border(box).BorderForeground(plain.GetForeground())
+		rightStyle = rightStyle.Border(box).BorderForeground(plain.GetForeground())
+		if a.focus == FocusNav {
+			navStyle = navStyle.BorderForeground(focusColor)
+		} else {
+			rightStyle = rightStyle.BorderForeground(focusColor)
+		}
+	}
+
+	switch a.panes {
+	case PanesNav:
+		return navStyle.Render(fitBlock(nav, navWidth, height))
+	case PanesViewer:
+		return rightStyle.Render(fitBlock(right, rightWidth, height))
+	}
+	panes := []string{navStyle.Render(fitBlock(nav, navWidth, height))}
+	if a.cfg.Border.Style == "line" && height > 0 {
+		arrow := "▶"
+		if a.focus == FocusNav {
+			arrow = "◀"
+		}
+		divider := lipgloss.NewStyle().Foreground(focusColor).Render(arrow)
+		if height > 1 {
+			divider += "\n" + plain.Render(strings.TrimSuffix(strings.Repeat("│\n", height-1), "\n"))
+		}
+		panes = append(panes, divider)
+	}
+	panes = append(panes, rightStyle.Render(fitBlock(right, rightWidth, height)))
+	return lipgloss.JoinHorizontal(lipgloss.Top, panes...)
+}
+
+// fitBlock cuts a pane's content to its size, so an overlong line or an
+// extra row can never wrap or push the borders out of line
+func fitBlock(s string, width, height int) string {
+	lines := strings.Split(s, "\n")
+	if len(lines) > height {
+		lines = lines[:height]
+	}
+	for i, line := range lines {
+		lines[i] = ansi.Truncate(line, width, "")
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// bracketPairs maps each opening bracket to its closing one
+var bracketPairs = map[rune]rune{'(': ')', '[': ']', '{': '}'}
+
+// bracketOpeners maps each closing bracket back to its opening one
+var bracketOpeners = map[rune]rune{')': '(', ']': '[', '}': '{'}
+
+// jumpBracket moves from the current line, the selection end or else the
+// top line, to the line of the match of its first bracket that closes on
+// another line, like vim's %. Brackets inside strings and comments are
+// counted too.
+func (t *TextViewer) jumpBracket() {
+	if t.index == nil {
+		return
+	}
+	from := t.offset
+	if t.visual {
+		from = t.cursor
+	}
+	lines, err := t.index.readLines(t.path, from, from+1)
+	if err != nil || len(lines) == 0 {
+		return
+	}
+	to, col := -1, -1
+	for i := range len(lines[0]) {
+		if !strings.ContainsRune("()[]{}", rune(lines[0][i])) {
+			continue
+		}
+		match, err := t.matchBracket(from, i)
+		if err != nil {
+			t.status = "Bracket search failed: " + err.Error()
+			return
+		}
+		if col < 0 || match >= 0 && match != from {
+			to, col = match, i
+		}
+		if to >= 0 && to != from {
+			break
+		}
+	}
+	switch {
+	case col < 0:
+		t.status = "No bracket on this line"
+		return
+	case to < 0:
+		t.status = fmt.Sprintf("No match for %c", lines[0][col])
+		return
+	}
+	if t.visual {
+		t.moveCursor(to - t.cursor)
+		return
+	}
+	t.scroll(to - t.offset)
+	t.status = fmt.Sprintf("%c matches line %d", lines[0][col], to+1)
+}
+
+// matchBracket returns the line holding the bracket that matches the one
+// at byte col of line, or -1 when it is unbalanced. The file is read a
+// window at a time in the direction of the search.
+func (t *TextViewer) matchBracket(line, col int) (int, error) {
+	lines, err := t.index.readLines(t.path, line, line+1)
+	if err != nil {
+		return -1, err
+	}
+	bracket := rune(lines[0][col])
+	match, forward := bracketPairs[bracket]
+	if !forward {
+		match = bracketOpeners[bracket]
+	}
+
+	// found scans text in the search direction for the match, counting
+	// nested pairs of the same kind on the way
+	depth := 0
+	found := func(text string) bool {
+		runes := []rune(text)
+		for k := range runes {
+			if !forward {
+				k = len(runes) - 1 - k
+			}
+			switch runes[k] {
+			case bracket:
+				depth++
+			case match:
+				if depth == 0 {
+					return true
+				}
+				depth--
+			}
+		}
+		return false
+	}
+
+	if forward {
+		if found(lines[0][col+1:]) {
+			return line, nil
+		}
+		for start := line + 1; start < t.lineCount(); start += textWindowLines {
+			chunk, err := t.index.readLines(t.path, start, min(start+textWindowLines, t.lineCount()))
+			if err != nil {
+				return -1, err
+			}
+			for i, text := range chunk {
+				if found(text) {
+					return start + i, nil
+				}
+			}
+		}
+		return -1, nil
+	}
+
+	if found(lines[0][:col]) {
+		return line, nil
+	}
+	for end := line; end > 0; end -= textWindowLines {
+		start := max(0, end-textWindowLines)
+		chunk, err := t.index.readLines(t.path, start, end)
+		if err != nil {
+			return -1, err
+		}
+		for i := len(chunk) - 1; i >= 0; i-- {
+			if found(chunk[i]) {
+				return start + i, nil
+			}
+		}
+	}
+	return -1, nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/atotto/clipboard"
+	"github.com/aymanbagabas/go-osc52/v2"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ClipboardMsg is sent after text was put on the clipboard
+type ClipboardMsg struct {
+	What string // description of what was copied, for the status line
+	Err  error
+}
+
+// Status describes the copy for a viewer's status line
+func (m ClipboardMsg) Status() string {
+	if m.Err != nil {
+		return "Copy failed: " + m.Err.Error()
+	}
+	return "Copied " + m.What
+}
+
+// copyToClipboard puts text on the system clipboard. Without a native
+// clipboard (e.g. over SSH) it asks the terminal to do it with OSC 52.
+func copyToClipboard(text, what string) tea.Cmd {
+	return func() tea.Msg {
+		err := clipboard.WriteAll(text)
+		if err != nil {
+			seq := osc52.New(text)
+			if os.Getenv("TMUX") != "" {
+				seq = seq.Tmux()
+			}
+			// stderr is the terminal even when stdout carries --print-path
+			_, err = seq.WriteTo(os.Stderr)
+		}
+		return ClipboardMsg{What: what, Err: err}
+	}
+}
+
+// yankFile copies a file's whole content to the clipboard
+func yankFile(path string) tea.Cmd {
+	return func() tea.Msg {
+		if err := checkSpecial(path); err != nil {
+			return ClipboardMsg{Err: err}
+		}
+		data, err := readContent(path)
+		if err != nil {
+			return ClipboardMsg{Err: err}
+		}
+		return copyToClipboard(string(data), filepath.Base(path))()
+	}
+}
+
+// yankLines copies lines to the clipboard, describing them by their
+// 0-based range [first, last]
+func yankLines(lines []string, first, last int) tea.Cmd {
+	what := fmt.Sprintf("line %d", first+1)
+	if last > first {
+		what = fmt.Sprintf("lines %d-%d", first+1, last+1)
+	}
+	return copyToClipboard(strings.Join(lines, "\n")+"\n", what)
+}
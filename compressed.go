@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxDecompressed caps how far a compressed file is inflated for viewing
+const maxDecompressed = 64 << 20
+
+// compressionExts maps the extensions of compressed files to their format
+var compressionExts = map[string]string{
+	".gz":  "gzip",
+	".bz2": "bzip2",
+}
+
+// compressionMagic starts files in each format, for those without the
+// extension
+var compressionMagic = map[string][]byte{
+	"gzip":  {0x1f, 0x8b},
+	"bzip2": []byte("BZh"),
+}
+
+// compressionExt returns the format a file's extension says it is
+// compressed in, or ""
+func compressionExt(path string) string {
+	return compressionExts[strings.ToLower(filepath.Ext(path))]
+}
+
+// innerPath is the name of what a compressed file holds, foo.json for
+// foo.json.gz, by which it is viewed. Other paths are returned as they are.
+func innerPath(path string) string {
+	if compressionExt(path) == "" {
+		return path
+	}
+	return strings.TrimSuffix(path, filepath.Ext(path))
+}
+
+// compressionOf returns the format path is compressed in, going by its
+// extension or, failing that, its first bytes, or ""
+func compressionOf(path string) string {
+	if format := compressionExt(path); format != "" {
+		return format
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	head := make([]byte, 3)
+	n, _ := io.ReadFull(f, head)
+	for format, magic := range compressionMagic {
+		if bytes.HasPrefix(head[:n], magic) {
+			return format
+		}
+	}
+	return ""
+}
+
+// compressedNote is the header note for a file shown decompressed
+func compressedNote(path string) string {
+	format := compressionExt(path)
+	if format == "" {
+		return ""
+	}
+	return "  " + format + " → " + filepath.Base(innerPath(path))
+}
+
+// readContent reads a file whole, decompressing it when its extension or
+// first bytes say it is compressed
+func readContent(path string) ([]byte, error) {
+	format := compressionOf(path)
+	if format == "" {
+		return os.ReadFile(path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var content io.Reader = bufio.NewReader(f)
+	switch format {
+	case "gzip":
+		gz, err := gzip.NewReader(content)
+		if err != nil {
+			return nil, fmt.Errorf("not valid gzip: %w", err)
+		}
+		content = gz
+	case "bzip2":
+		content = bzip2.NewReader(content)
+	}
+	data, err := io.ReadAll(io.LimitReader(content, maxDecompressed+1))
+	if err != nil {
+		return nil, fmt.Errorf("decompressing %s: %w", format, err)
+	}
+	if len(data) > maxDecompressed {
+		return nil, fmt.Errorf("more than %s uncompressed, too large to view", humanSize(maxDecompressed))
+	}
+	return data, nil
+}
+
+// decompressedLines reads a compressed file for the text viewer, which
+// cannot read it from disk a window at a time, so holds all its lines.
+// Binary content gets the escaped preview binary files do.
+func decompressedLines(path string, limit int) FileLoadedMsg {
+	data, err := readContent(path)
+	if err != nil {
+		return FileLoadedMsg{Path: path, Err: err}
+	}
+	format := detectFormat(data)
+	text, shown := string(data), int64(0)
+	if format.encoding == "binary" {
+		shown = int64(min(len(data), limit))
+		text = escapeBinary(data[:shown])
+	}
+	lines := strings.Split(text, "\n")
+	return FileLoadedMsg{Path: path, Index: heldIndex(lines, int64(len(data)), format), Shown: shown}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// TestYankFromCompressedFile selects and yanks lines, and matches a
+// bracket, in a gzipped file, whose lines must come from the decompressed
+// content rather than from offsets into the file on disk
+func TestYankFromCompressedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.txt.gz")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(f)
+	gz.Write([]byte("first (line\nsecond line\nthird) line\n"))
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	v := NewTextViewer(nil)
+	v.SetSize(60, 10)
+	v.SetFocused(true)
+	settle(v, v.Load(path))
+	if v.err != nil {
+		t.Fatalf("load failed: %v", v.err)
+	}
+
+	for _, key := range []string{"V", "%"} {
+		v.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+	}
+	first, last := v.selection()
+	lines, err := v.index.readLines(v.path, first, last+1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"first (line", "second line", "third) line"}
+	if !slices.Equal(lines, want) {
+		t.Errorf("selected lines %q, want %q", lines, want)
+	}
+	// The copy itself is not run, so the test leaves the clipboard alone
+	if _, cmd := v.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")}); cmd == nil || v.status != "" {
+		t.Errorf("yank failed: %s", v.status)
+	}
+}
@@ -0,0 +1,550 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Config holds user preferences, loaded from config.json in the dmc-nav
+// user config directory. Keys missing from the file keep their defaults.
+type Config struct {
+	Editor   EditorConfig   `json:"editor"`
+	Markdown MarkdownConfig `json:"markdown"`
+	JSON     JSONConfig     `json:"json"`
+	// Viewers maps a file extension, e.g. ".json", to the viewer that
+	// opens it: "markdown", "json", "keyvalue", "hex" or "text". Other
+	// extensions use the viewer that recognizes them.
+	Viewers map[string]string `json:"viewers"`
+	// Enter maps a file extension to what selecting such a file does:
+	// "view" previews it, as for unlisted extensions, "edit" opens it
+	// straight in the editor, "open" hands it to the system default
+	// application and "hex" shows the start of it as a hex dump. The
+	// "binary" entry is for unlisted files that hold NUL bytes.
+	Enter map[string]string `json:"enter"`
+	// Filetypes maps a file name or glob pattern, e.g. "Makefile" or
+	// "Dockerfile.*", to its language: a highlighter name such as "make"
+	// or "bash", or "text". Files typed "markdown" or "json" open in
+	// those viewers. Common names are recognized without configuration.
+	Filetypes map[string]string `json:"filetypes"`
+	// Confirm turns off the question asked before an action by mapping it
+	// to false: "delete" for deleting files, "discard" for throwing away
+	// editor changes, "overwrite" for writing over a file that exists or
+	// changed on disk, and "open_link" for opening a link in the browser.
+	// Every action asks unless listed.
+	Confirm   map[string]bool `json:"confirm"`
+	Nav       NavConfig       `json:"nav"`
+	Format    FormatConfig    `json:"format"`
+	Sort      SortConfig      `json:"sort"`
+	Scrollbar ScrollbarConfig `json:"scrollbar"`
+	// ScrollOff is the number of lines kept visible above and below the
+	// cursor in the nav and viewers
+	ScrollOff int `json:"scrolloff"`
+	// Prefetch is how many lines short of the end of the lines read into
+	// memory the text viewer starts reading more in the background, so
+	// fast scrolling through large files does not stall. 0 reads only
+	// when lines are needed.
+	Prefetch int `json:"prefetch"`
+	// BinaryPreview is how many bytes from the start of a binary file the
+	// text viewer shows, with bytes that are not text escaped as \xNN
+	BinaryPreview int `json:"binary_preview"`
+	// MaxWidth caps the width of text and markdown in the viewer; in a
+	// wider pane the content is centered. 0 uses the full width.
+	MaxWidth int `json:"max_width"`
+	// Cursor is how the row under the cursor is drawn in the nav and
+	// every viewer
+	Cursor CursorConfig `json:"cursor"`
+	// WrapAround makes j on the last row of the nav or JSON tree go to the
+	// first, and k on the first go to the last
+	WrapAround bool         `json:"wrap_around"`
+	Search     SearchConfig `json:"search"`
+	Border     BorderConfig `json:"border"`
+	// Encoding shows the detected encoding and line endings of a file,
+	// e.g. "UTF-8 LF", in the text viewer header and editor status line
+	Encoding EncodingConfig `json:"encoding"`
+	// GoToTop is the key for the top of the nav and viewers: "g", or
+	// "gg" as in vim, where a lone g does nothing. G goes to the bottom
+	// either way.
+	GoToTop string `json:"go_to_top"`
+	// FocusOrder is the panes tab moves focus through, "nav" and
+	// "viewer", and shift+tab moves through backwards. In the editor both
+	// keys are typed into the file.
+	FocusOrder []string `json:"focus_order"`
+	// NonTTY is what happens when the output is not a terminal, e.g.
+	// piped: "error" exits saying so, "render" prints one frame as
+	// --render does, at 80x24
+	NonTTY string `json:"non_tty"`
+}
+
+// SortConfig is the order of the nav tree and directory listings. In a
+// listing, s, r and f change it from there.
+type SortConfig struct {
+	// By is "name", "size" or "modified"
+	By string `json:"by"`
+	// Reverse sorts Z to A, largest or newest first
+	Reverse bool `json:"reverse"`
+	// DirsFirst lists directories ahead of files rather than among them
+	DirsFirst bool `json:"dirs_first"`
+}
+
+// EncodingConfig controls the encoding and line-ending badge
+type EncodingConfig struct {
+	Show  bool   `json:"show"`
+	Color string `json:"color"`
+}
+
+// BorderConfig controls the borders between and around the panes
+type BorderConfig struct {
+	// Style is "line" (a divider between the panes, its top pointing at
+	// the focused one), "normal", "rounded", "thick" or "double" (a box
+	// around each pane, the focused one in FocusColor) or "none"
+	Style      string `json:"style"`
+	Color      string `json:"color"` // empty keeps the terminal's
+	FocusColor string `json:"focus_color"`
+}
+
+// SearchConfig controls matching in searches
+type SearchConfig struct {
+	// Case is "smart" (ignore case unless the query has a capital letter),
+	// "sensitive" or "insensitive". Ctrl+T switches it while searching.
+	Case string `json:"case"`
+}
+
+// CursorConfig styles the row under the cursor. Colors are ANSI numbers
+// such as "62" or hex such as "#5f5fd7"; empty keeps the terminal's.
+type CursorConfig struct {
+	// Fill is "row" to highlight the full pane width or "text" for the
+	// text only
+	Fill       string `json:"fill"`
+	Background string `json:"background"`
+	Foreground string `json:"foreground"`
+	Bold       bool   `json:"bold"`
+	// Reverse swaps the colors, which stays visible on any theme
+	Reverse bool `json:"reverse"`
+}
+
+// NavConfig controls the file tree
+type NavConfig struct {
+	// Hide lists glob patterns, matched against entry names, for files
+	// and directories left out of the tree and the finder. A pattern
+	// starting with "!" shows matching names again; the last matching
+	// pattern wins. The config's patterns follow the defaults rather
+	// than replacing them, so "!.*" shows dotfiles again.
+	Hide []string `json:"hide"`
+	// Badges shows a note at the right of entries: ★ for uncommitted git
+	// changes, the line count of small text files, "img" or "bin". Off by
+	// default, since they read files and run git before a listing shows.
+	// Toggle with i.
+	Badges bool `json:"badges"`
+	// BadgeColor is the badges' color, an ANSI number or hex
+	BadgeColor string `json:"badge_color"`
+	// StartupExpand is how much of the tree opens at startup: "cwd"
+	// expands down to the working directory, "none" shows only the
+	// root's children, and a number opens that many levels below the
+	// root. A path given on the command line is always revealed.
+	StartupExpand string `json:"startup_expand"`
+	// Left is what h and left do on an entry that is not an expanded
+	// directory: "collapse" moves up to its parent in the tree and
+	// collapses it, re-rooting only from a top-level entry; "reroot"
+	// re-roots the tree at the root's parent straight away. Backspace
+	// does the other one.
+	Left string `json:"left"`
+	// Enter is what enter, l and right do on a directory: "toggle"
+	// expands or collapses it in place, "descend" re-roots the tree at
+	// it. z expands and collapses in place either way.
+	Enter string `json:"enter"`
+	// FocusOnOpen moves focus to the viewer when enter opens a file, as
+	// L always does, so it can be scrolled straight away
+	FocusOnOpen bool `json:"focus_on_open"`
+	// Truncate is where names too long for the pane are cut: "end" or
+	// "middle", which keeps the extension when it fits. The selected
+	// entry's full name is shown at the bottom.
+	Truncate string `json:"truncate"`
+}
+
+// MarkdownConfig controls the markdown viewer
+type MarkdownConfig struct {
+	// Plain starts documents in plain mode, showing the source line for
+	// line instead of glamour's rendering
+	Plain bool `json:"plain"`
+	// CacheSize is how many renderings are kept, so going back to a
+	// document, or a pane to an earlier width, shows it at once. 0
+	// renders every time.
+	CacheSize int `json:"cache_size"`
+}
+
+// EditorConfig controls the editor
+type EditorConfig struct {
+	// Save is applied to every file unless overridden by extension
+	Save SaveRules `json:"save"`
+	// SaveOverrides replaces individual Save fields per extension, e.g.
+	// ".md", where trailing spaces are line breaks
+	SaveOverrides map[string]SaveOverride `json:"save_overrides"`
+	// Templates is the directory of templates for files created with N,
+	// named by extension, e.g. "go" or "md". Empty means templates in the
+	// config directory.
+	Templates string `json:"templates"`
+	// AutoSave saves without asking; it is off unless set
+	AutoSave AutoSaveConfig `json:"auto_save"`
+	// WrapMarker marks the rows that continue a soft-wrapped line in the
+	// line-number gutter, which otherwise only leaves them unnumbered
+	WrapMarker WrapMarkerConfig `json:"wrap_marker"`
+}
+
+// WrapMarkerConfig controls the editor's continuation marker
+type WrapMarkerConfig struct {
+	Show  bool   `json:"show"`
+	Glyph string `json:"glyph"` // a single character
+	Color string `json:"color"`
+}
+
+// AutoSaveConfig controls when the editor saves on its own
+type AutoSaveConfig struct {
+	// OnLeave saves edits when leaving the editor with esc instead of
+	// asking whether to discard them
+	OnLeave bool `json:"on_leave"`
+	// IdleSeconds saves after that long without typing; 0 never does
+	IdleSeconds int `json:"idle_seconds"`
+}
+
+// SaveRules are transforms applied to the buffer before it is written
+type SaveRules struct {
+	TrimTrailingWhitespace bool `json:"trim_trailing_whitespace"`
+	EnsureFinalNewline     bool `json:"ensure_final_newline"`
+}
+
+// SaveOverride sets only the SaveRules fields present
+type SaveOverride struct {
+	TrimTrailingWhitespace *bool `json:"trim_trailing_whitespace,omitempty"`
+	EnsureFinalNewline     *bool `json:"ensure_final_newline,omitempty"`
+}
+
+// ScrollbarConfig controls the scrollbar drawn on the right edge of the
+// nav and viewers when their content does not fit
+type ScrollbarConfig struct {
+	Show bool `json:"show"`
+	// TrackColor and ThumbColor are ANSI numbers or hex; the thumb marks
+	// the part in view
+	TrackColor string `json:"track_color"`
+	ThumbColor string `json:"thumb_color"`
+}
+
+// FormatConfig controls how sizes and times are shown in listings
+type FormatConfig struct {
+	// Sizes is "human" for rounded sizes such as 1.2M or "bytes" for
+	// exact byte counts
+	Sizes string `json:"sizes"`
+	// Times is "absolute" for timestamps or "relative" for ages such as
+	// "3 hours ago"
+	Times string `json:"times"`
+}
+
+// JSONConfig controls the JSON viewer
+type JSONConfig struct {
+	Numbers NumberFormat `json:"numbers"`
+	// MaxDepth is how many levels of nesting are built when a document
+	// loads; deeper containers show as "…(deeper)" and are built when
+	// opened
+	MaxDepth int `json:"max_depth"`
+}
+
+// NumberFormat controls how JSON numbers are displayed. Numbers are
+// always kept exactly as written in the file, so formatting never changes
+// what is saved.
+type NumberFormat struct {
+	// Style is "general" (shortest exact form, exponent for very large or
+	// small values), "fixed" (never an exponent) or "raw" (the source
+	// token as written)
+	Style string `json:"style"`
+	// Precision is the number of decimals in fixed style; -1, the
+	// default, uses as many as needed
+	Precision int `json:"precision"`
+	// Thousands groups the integer part in threes with commas
+	Thousands bool `json:"thousands"`
+}
+
+// DefaultConfig returns the configuration used when no file is present
+func DefaultConfig() Config {
+	keepSpaces := false
+	return Config{
+		Editor: EditorConfig{
+			WrapMarker: WrapMarkerConfig{Show: true, Glyph: "↪", Color: "240"},
+			SaveOverrides: map[string]SaveOverride{
+				".md":       {TrimTrailingWhitespace: &keepSpaces},
+				".markdown": {TrimTrailingWhitespace: &keepSpaces},
+			},
+		},
+		Markdown: MarkdownConfig{CacheSize: defaultMarkdownCacheSize},
+		JSON: JSONConfig{
+			Numbers:  NumberFormat{Style: "general", Precision: -1},
+			MaxDepth: defaultJSONMaxDepth,
+		},
+		Format:    FormatConfig{Sizes: "human", Times: "absolute"},
+		Sort:      SortConfig{By: "name", DirsFirst: true},
+		Enter:     map[string]string{enterBinary: "hex"},
+		Scrollbar: ScrollbarConfig{Show: true, TrackColor: "238", ThumbColor: "245"},
+		Nav: NavConfig{
+			Hide:          []string{".*", "!.git"},
+			BadgeColor:    "245",
+			StartupExpand: "cwd",
+			Left:          "collapse",
+			Enter:         "toggle",
+			Truncate:      "end",
+		},
+		ScrollOff:     defaultScrollOff,
+		Prefetch:      defaultPrefetch,
+		BinaryPreview: defaultBinaryPreview,
+		FocusOrder:    []string{"nav", "viewer"},
+		GoToTop:       "g",
+		NonTTY:        "error",
+		Encoding:      EncodingConfig{Color: "245"},
+		Search:        SearchConfig{Case: "smart"},
+		Border:        BorderConfig{Style: "line", FocusColor: "62"},
+		Cursor: CursorConfig{
+			Fill:       "row",
+			Background: "62",
+			Foreground: "230",
+			Bold:       true,
+		},
+	}
+}
+
+// ConfigPath returns where the config file is read from
+func ConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "dmc-nav", "config.json"), nil
+}
+
+// LoadConfig reads the config file over the defaults. A missing file is
+// not an error.
+func LoadConfig(path string) (Config, error) {
+	cfg := DefaultConfig()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+	defaultHide := cfg.Nav.Hide
+	cfg.Nav.Hide = nil
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("%s: %w", path, err)
+	}
+	cfg.Nav.Hide = append(defaultHide, cfg.Nav.Hide...)
+	switch cfg.JSON.Numbers.Style {
+	case "general", "fixed", "raw":
+	default:
+		return cfg, fmt.Errorf("%s: json.numbers.style must be general, fixed or raw, not %q", path, cfg.JSON.Numbers.Style)
+	}
+	if _, err := parseStartupExpand(cfg.Nav.StartupExpand); err != nil {
+		return cfg, fmt.Errorf("%s: nav.startup_expand: %v", path, err)
+	}
+	if _, ok := parseCaseMode(cfg.Search.Case); !ok {
+		return cfg, fmt.Errorf("%s: search.case must be smart, sensitive or insensitive, not %q", path, cfg.Search.Case)
+	}
+	if _, ok := boxBorders[cfg.Border.Style]; !ok && cfg.Border.Style != "line" && cfg.Border.Style != "none" {
+		return cfg, fmt.Errorf("%s: border.style must be line, normal, rounded, thick, double or none, not %q", path, cfg.Border.Style)
+	}
+	if cfg.Format.Sizes != "human" && cfg.Format.Sizes != "bytes" {
+		return cfg, fmt.Errorf("%s: format.sizes must be human or bytes, not %q", path, cfg.Format.Sizes)
+	}
+	if _, ok := parseDirSort(cfg.Sort.By); !ok {
+		return cfg, fmt.Errorf("%s: sort.by must be name, size or modified, not %q", path, cfg.Sort.By)
+	}
+	if cfg.Format.Times != "absolute" && cfg.Format.Times != "relative" {
+		return cfg, fmt.Errorf("%s: format.times must be absolute or relative, not %q", path, cfg.Format.Times)
+	}
+	if cfg.Editor.AutoSave.IdleSeconds < 0 {
+		return cfg, fmt.Errorf("%s: editor.auto_save.idle_seconds must not be negative, not %d", path, cfg.Editor.AutoSave.IdleSeconds)
+	}
+	if _, err := parseFocusOrder(cfg.FocusOrder); err != nil {
+		return cfg, fmt.Errorf("%s: focus_order: %v", path, err)
+	}
+	if _, err := parseTruncation(cfg.Nav.Truncate); err != nil {
+		return cfg, fmt.Errorf("%s: nav.truncate: %v", path, err)
+	}
+	if cfg.Nav.Left != "collapse" && cfg.Nav.Left != "reroot" {
+		return cfg, fmt.Errorf("%s: nav.left must be collapse or reroot, not %q", path, cfg.Nav.Left)
+	}
+	if cfg.Nav.Enter != "toggle" && cfg.Nav.Enter != "descend" {
+		return cfg, fmt.Errorf("%s: nav.enter must be toggle or descend, not %q", path, cfg.Nav.Enter)
+	}
+	if cfg.GoToTop != "g" && cfg.GoToTop != "gg" {
+		return cfg, fmt.Errorf("%s: go_to_top must be g or gg, not %q", path, cfg.GoToTop)
+	}
+	if utf8.RuneCountInString(cfg.Editor.WrapMarker.Glyph) != 1 {
+		return cfg, fmt.Errorf("%s: editor.wrap_marker.glyph must be a single character, not %q", path, cfg.Editor.WrapMarker.Glyph)
+	}
+	if cfg.NonTTY != "error" && cfg.NonTTY != "render" {
+		return cfg, fmt.Errorf("%s: non_tty must be error or render, not %q", path, cfg.NonTTY)
+	}
+	if cfg.Markdown.CacheSize < 0 {
+		return cfg, fmt.Errorf("%s: markdown.cache_size must not be negative, not %d", path, cfg.Markdown.CacheSize)
+	}
+	if cfg.BinaryPreview < 1 {
+		return cfg, fmt.Errorf("%s: binary_preview must be at least 1, not %d", path, cfg.BinaryPreview)
+	}
+	if cfg.Prefetch < 0 {
+		return cfg, fmt.Errorf("%s: prefetch must not be negative, not %d", path, cfg.Prefetch)
+	}
+	if cfg.MaxWidth < 0 {
+		return cfg, fmt.Errorf("%s: max_width must not be negative, not %d", path, cfg.MaxWidth)
+	}
+	if cfg.JSON.MaxDepth < 1 {
+		return cfg, fmt.Errorf("%s: json.max_depth must be at least 1, not %d", path, cfg.JSON.MaxDepth)
+	}
+	switch cfg.Cursor.Fill {
+	case "row", "text":
+	default:
+		return cfg, fmt.Errorf("%s: cursor.fill must be row or text, not %q", path, cfg.Cursor.Fill)
+	}
+	for _, pattern := range cfg.Nav.Hide {
+		if _, err := filepath.Match(strings.TrimPrefix(pattern, "!"), ""); err != nil {
+			return cfg, fmt.Errorf("%s: nav.hide pattern %q: %w", path, pattern, err)
+		}
+	}
+	for ext, name := range cfg.Viewers {
+		switch name {
+		case "markdown", "json", "keyvalue", "hex", "text":
+		default:
+			return cfg, fmt.Errorf("%s: viewers[%q] must be markdown, json, keyvalue, hex or text, not %q", path, ext, name)
+		}
+	}
+	for ext, action := range cfg.Enter {
+		switch action {
+		case "view", "edit", "open", "hex":
+		default:
+			return cfg, fmt.Errorf("%s: enter[%q] must be view, edit, open or hex, not %q", path, ext, action)
+		}
+	}
+	for action := range cfg.Confirm {
+		if !slices.Contains(confirmActions, action) {
+			return cfg, fmt.Errorf("%s: confirm[%q] must be one of %s", path, action, strings.Join(confirmActions, ", "))
+		}
+	}
+	for pattern, lang := range cfg.Filetypes {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return cfg, fmt.Errorf("%s: filetypes pattern %q: %w", path, pattern, err)
+		}
+		if !validFiletype(lang) {
+			return cfg, fmt.Errorf("%s: filetypes[%q]: unknown language %q", path, pattern, lang)
+		}
+	}
+	return cfg, nil
+}
+
+// ViewerFor returns the viewer configured for path's extension, or the
+// one its file type calls for, or ""
+func (c Config) ViewerFor(path string) string {
+	if name, ok := extEntry(c.Viewers, path); ok {
+		return name
+	}
+	switch lang := c.FiletypeFor(path); lang {
+	case "markdown", "json":
+		return lang
+	}
+	return ""
+}
+
+// enterBinary is the Enter entry for files that look binary
+const enterBinary = "binary"
+
+// EnterAction returns what selecting the file at path does: "view",
+// "edit", "open" or "hex". Files whose extension is not listed take the
+// "binary" entry when their content looks binary.
+func (c Config) EnterAction(path string) string {
+	if action, ok := c.enterForExt(path); ok {
+		return action
+	}
+	// Compressed files, unless listed themselves, go by what they hold
+	if inner := innerPath(path); inner != path {
+		if action, ok := c.enterForExt(inner); ok {
+			return action
+		}
+		return "view"
+	}
+	if action, ok := c.Enter[enterBinary]; ok && looksBinary(path) && compressionOf(path) == "" {
+		return action
+	}
+	return "view"
+}
+
+// enterForExt returns the Enter entry for path's extension, if it has one
+func (c Config) enterForExt(path string) (string, bool) {
+	if filepath.Ext(path) == "."+enterBinary {
+		return "", false // the binary entry is not an extension
+	}
+	return extEntry(c.Enter, path)
+}
+
+// extEntry returns what m lists for path's extension. Keys match with or
+// without the dot and in any case; when several do, such as "json" and
+// ".JSON", the key written just as the extension is wins, then keys with the
+// dot before those without, then sorted order, so the choice never rests
+// on map order.
+func extEntry(m map[string]string, path string) (string, bool) {
+	ext := filepath.Ext(path)
+	if ext == "" {
+		return "", false
+	}
+	rank := func(key string) int {
+		switch {
+		case key == ext:
+			return 0
+		case strings.HasPrefix(key, "."):
+			return 1
+		}
+		return 2
+	}
+	var keys []string
+	for key := range m {
+		if lower := strings.ToLower(key); lower == strings.ToLower(ext) || "."+lower == strings.ToLower(ext) {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return "", false
+	}
+	slices.SortFunc(keys, func(a, b string) int {
+		return cmp.Or(rank(a)-rank(b), strings.Compare(a, b))
+	})
+	return m[keys[0]], true
+}
+
+// Confirms reports whether action asks before going ahead
+func (c Config) Confirms(action string) bool {
+	ask, ok := c.Confirm[action]
+	return !ok || ask
+}
+
+// FiletypeFor returns the language of path known from its name, or ""
+func (c Config) FiletypeFor(path string) string {
+	return filetypeFor(path, c.Filetypes)
+}
+
+// SaveRulesFor resolves the save rules for a path from the defaults and
+// any override for its extension
+func (c EditorConfig) SaveRulesFor(path string) SaveRules {
+	rules := c.Save
+	o, ok := c.SaveOverrides[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return rules
+	}
+	if o.TrimTrailingWhitespace != nil {
+		rules.TrimTrailingWhitespace = *o.TrimTrailingWhitespace
+	}
+	if o.EnsureFinalNewline != nil {
+		rules.EnsureFinalNewline = *o.EnsureFinalNewline
+	}
+	return rules
+}
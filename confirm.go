@@ -0,0 +1,63 @@
+package main
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// confirmActions are the kinds of action that ask before going ahead,
+// each of which the confirm config can turn off
+var confirmActions = []string{"delete", "discard", "overwrite", "open_link"}
+
+// ConfirmMsg asks a yes/no question on the bottom line. Until it is
+// answered the App takes y, n and esc and nothing else, then runs Yes or
+// No on the event loop, so they may change the asking pane's state.
+// Questions for an action the config does not confirm run Yes at once.
+type ConfirmMsg struct {
+	Action   string // one of confirmActions
+	Question string
+	Yes      func() tea.Cmd
+	No       func() tea.Cmd // also run for esc; nil does nothing
+}
+
+// askConfirm returns a command asking question before action, running
+// yes or no with the answer
+func askConfirm(action, question string, yes, no func() tea.Cmd) tea.Cmd {
+	return func() tea.Msg {
+		return ConfirmMsg{Action: action, Question: question, Yes: yes, No: no}
+	}
+}
+
+// updateConfirm answers the open question from a key, ignoring keys that
+// are not an answer
+func (a *App) updateConfirm(msg tea.KeyMsg) tea.Cmd {
+	answer := a.confirm.No
+	switch msg.String() {
+	case "y", "Y":
+		answer = a.confirm.Yes
+	case "n", "N", "esc":
+	case "ctrl+c":
+		return tea.Quit
+	default:
+		return nil
+	}
+	a.confirm = nil
+	if answer == nil {
+		return nil
+	}
+	return answer()
+}
+
+// overlayConfirm puts the open question over the last line of the frame
+func (a *App) overlayConfirm(frame string) string {
+	lines := strings.Split(frame, "\n")
+	prompt := ansi.Truncate(a.confirm.Question+" (y/n)", a.width, "…")
+	lines[len(lines)-1] = lipgloss.NewStyle().
+		Foreground(lipgloss.Color("214")).
+		Width(a.width).
+		Render(prompt)
+	return strings.Join(lines, "\n")
+}
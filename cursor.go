@@ -0,0 +1,39 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// cursorStyle draws the row under the cursor, the same way in the nav and
+// every viewer
+type cursorStyle struct {
+	style   lipgloss.Style
+	fullRow bool // pad the highlight to the pane width
+}
+
+// defaultCursor is the style panes start with until configured
+var defaultCursor = newCursorStyle(DefaultConfig().Cursor)
+
+func newCursorStyle(c CursorConfig) cursorStyle {
+	style := lipgloss.NewStyle().Bold(c.Bold).Reverse(c.Reverse)
+	if c.Background != "" {
+		style = style.Background(lipgloss.Color(c.Background))
+	}
+	if c.Foreground != "" {
+		style = style.Foreground(lipgloss.Color(c.Foreground))
+	}
+	return cursorStyle{style: style, fullRow: c.Fill == "row"}
+}
+
+// render highlights a line for a pane width cells wide. The line's own
+// colors are dropped so the highlight reads as one band.
+func (c cursorStyle) render(line string, width int) string {
+	line = ansi.Strip(line)
+	if c.fullRow {
+		line += strings.Repeat(" ", max(0, width-ansi.StringWidth(line)))
+	}
+	return c.style.Render(line)
+}
@@ -1,15 +1,29 @@
 package main
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/textarea"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
 )
 
+// noWrapWidth is the textarea width used when soft wrap is off: the
+// textarea always wraps, so lines are laid out this wide and clipped to the
+// pane when rendered. It matches the textarea's default MaxWidth.
+const noWrapWidth = 500
+
+// eobChar starts the gutter of the rows past the end of the buffer. It
+// shows as a space but tells them apart from wrapped rows, whose gutter is
+// all spaces.
+const eobChar = '\u00a0'
+
 // Editor is a simple text editor using textarea
 type Editor struct {
 	width   int
@@ -19,16 +33,32 @@ type Editor struct {
 	path     string
 	textarea textarea.Model
 	modified bool
+	edits    int // counts changes to the text, to tell which buffer a save or idle timer saw
 	err      error
 	status   string
+	notice   string // status to show once the next file opens
+
+	noWrap  bool // clip long lines instead of soft-wrapping them
+	hscroll int  // horizontal scroll in columns when noWrap is set
+
+	cfg EditorConfig
+
+	// File state when opened, to detect changes made by other programs
+	openedModTime time.Time
+	openedSize    int64
+	existed       bool // the file was present when opened
+	format        fileFormat
+	formatBadge   formatBadge
 }
 
-func NewEditor() *Editor {
+func NewEditor(cfg EditorConfig) *Editor {
 	ta := textarea.New()
 	ta.ShowLineNumbers = true
+	ta.EndOfBufferCharacter = eobChar
 	ta.CharLimit = 0 // unlimited
 	return &Editor{
 		textarea: ta,
+		cfg:      cfg,
 	}
 }
 
@@ -46,8 +76,49 @@ func (e *Editor) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		e.textarea.Focus()
 		e.modified = false
 		e.err = msg.Err
-		e.status = ""
+		e.status = e.notice
+		e.notice = ""
+		e.existed = msg.Info != nil
+		e.format = detectFormat([]byte(msg.Content))
+		if msg.Info != nil {
+			e.openedModTime = msg.Info.ModTime()
+			e.openedSize = msg.Info.Size()
+		}
 		e.updateSize()
+		if msg.Line > 0 {
+			e.gotoLine(msg.Line)
+			if msg.Col > 0 {
+				e.textarea.SetCursor(msg.Col - 1)
+				e.followCursor()
+			}
+		}
+		return e, nil
+
+	case EditorSavedMsg:
+		if msg.Path != e.path {
+			return e, nil
+		}
+		if msg.Err != nil {
+			e.status = "Save failed, your edits are kept: " + msg.Err.Error()
+			return e, nil
+		}
+		// Keys typed while the file was being written are still unsaved
+		e.modified = e.edits != msg.Edit
+		e.status = ""
+		if msg.Kind == saveIdle {
+			e.status = "Auto-saved at " + time.Now().Format("15:04:05")
+		}
+		if info, err := os.Stat(msg.Path); err == nil {
+			e.existed = true
+			e.openedModTime = info.ModTime()
+			e.openedSize = info.Size()
+		}
+		return e, nil
+
+	case EditorIdleMsg:
+		if e.focused && e.modified && msg.Path == e.path && msg.Edit == e.edits {
+			return e, e.save(saveIdle)
+		}
 		return e, nil
 
 	case tea.KeyMsg:
@@ -60,9 +131,24 @@ func (e *Editor) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// Handle commands
 		switch key {
 		case "ctrl+s":
-			return e, e.save()
+			return e, e.save(saveAsked)
+		case "ctrl+r":
+			return e, e.reload()
 		case "esc":
+			if e.modified && e.cfg.AutoSave.OnLeave {
+				return e, e.save(saveOnLeave)
+			}
+			if e.modified {
+				return e, e.ask("discard", "Discard unsaved changes?", "", e.cancel)
+			}
 			return e, e.cancel()
+		case "ctrl+g":
+			return e, askInput("Go to line: ", "", e.goToTyped)
+		case "alt+z":
+			e.noWrap = !e.noWrap
+			e.hscroll = 0
+			e.updateSize()
+			return e, nil
 		}
 
 		// Check for :w command (vim-style save)
@@ -71,13 +157,25 @@ func (e *Editor) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			// For now, just pass through
 		}
 
-		// Update textarea
+		// Update textarea; only keys that change the text count as edits
+		before := e.textarea.Value()
 		var cmd tea.Cmd
 		e.textarea, cmd = e.textarea.Update(msg)
 		if cmd != nil {
 			cmds = append(cmds, cmd)
 		}
+		e.followCursor()
+		if e.textarea.Value() == before {
+			break
+		}
 		e.modified = true
+		e.edits++
+		if idle := e.cfg.AutoSave.IdleSeconds; idle > 0 {
+			path, edit := e.path, e.edits
+			cmds = append(cmds, tea.Tick(time.Duration(idle)*time.Second, func(time.Time) tea.Msg {
+				return EditorIdleMsg{Path: path, Edit: edit}
+			}))
+		}
 
 	default:
 		var cmd tea.Cmd
@@ -111,24 +209,107 @@ func (e *Editor) View() string {
 	// Status bar
 	statusStyle := lipgloss.NewStyle().
 		Foreground(lipgloss.Color("245"))
-	status := statusStyle.Render("Ctrl+S: save | Esc: cancel")
+	row, col := e.cursorPosition()
+	position := fmt.Sprintf("Ln %d, Col %d", row, col)
+	if e.noWrap {
+		position += " | nowrap"
+	}
+	text := "Ctrl+S: save | Ctrl+R: reload | Ctrl+G: go to line | Alt+Z: wrap | Esc: cancel"
 	if e.status != "" {
-		status = statusStyle.Render(e.status)
+		text = e.status
+	}
+	status := statusStyle.Render(position + " | ")
+	if badge := e.formatBadge.render(e.format); badge != "" {
+		status += badge + statusStyle.Render(" | ")
+	}
+	status += statusStyle.Render(text)
+
+	return header + "\n" + e.textareaView() + "\n" + status
+}
+
+// textareaView renders the textarea, clipping rows to the pane around the
+// horizontal scroll position when soft wrap is off. The prompt and
+// line-number gutter stay fixed on the left.
+func (e *Editor) textareaView() string {
+	view := e.textarea.View()
+	if !e.noWrap {
+		return e.markWrapped(view)
+	}
+	gutter := e.gutterWidth()
+	visible := max(1, e.width-gutter)
+	rows := strings.Split(view, "\n")
+	for i, row := range rows {
+		rows[i] = ansi.Truncate(row, gutter, "") + ansi.Cut(row, gutter+e.hscroll, gutter+e.hscroll+visible)
+	}
+	return strings.Join(rows, "\n")
+}
+
+// markWrapped puts the wrap marker in the line-number gutter of rows that
+// continue a soft-wrapped line, right-aligned as the numbers are
+func (e *Editor) markWrapped(view string) string {
+	marker := e.cfg.WrapMarker
+	if !marker.Show || !e.textarea.ShowLineNumbers {
+		return view
 	}
+	prompt, gutter := lipgloss.Width(e.textarea.Prompt), e.gutterWidth()
+	blank := strings.Repeat(" ", gutter-prompt)
+	pad := strings.Repeat(" ", max(0, gutter-prompt-1-lipgloss.Width(marker.Glyph)))
+	glyph := lipgloss.NewStyle().Foreground(lipgloss.Color(marker.Color)).Render(marker.Glyph)
+	rows := strings.Split(view, "\n")
+	for i, row := range rows {
+		if ansi.Strip(ansi.Cut(row, prompt, gutter)) != blank {
+			continue
+		}
+		rows[i] = ansi.Truncate(row, prompt, "") + pad + glyph + " " + ansi.Cut(row, gutter, ansi.StringWidth(row))
+	}
+	return strings.Join(rows, "\n")
+}
+
+// gutterWidth is the width the textarea reserves for its prompt and line
+// numbers
+func (e *Editor) gutterWidth() int {
+	w := lipgloss.Width(e.textarea.Prompt)
+	if e.textarea.ShowLineNumbers {
+		w += 4 // matches the textarea's own reservation in SetWidth
+	}
+	return w
+}
 
-	return header + "\n" + e.textarea.View() + "\n" + status
+// followCursor keeps the cursor column inside the clipped view when soft
+// wrap is off
+func (e *Editor) followCursor() {
+	if !e.noWrap {
+		return
+	}
+	visible := max(1, e.width-e.gutterWidth())
+	col := e.textarea.LineInfo().CharOffset
+	if col < e.hscroll {
+		e.hscroll = col
+	}
+	if col >= e.hscroll+visible {
+		e.hscroll = col - visible + 1
+	}
 }
 
 func (e *Editor) SetSize(width, height int) {
+	if width == e.width && height == e.height {
+		return
+	}
 	e.width = width
 	e.height = height
 	e.updateSize()
 }
 
 func (e *Editor) updateSize() {
-	// Account for header and status line
-	e.textarea.SetWidth(e.width)
+	// Account for header and status line. The textarea counts wrapped rows
+	// against its own height, so wrapping needs no extra room here.
+	if e.noWrap {
+		e.textarea.SetWidth(noWrapWidth)
+	} else {
+		e.textarea.SetWidth(e.width)
+	}
 	e.textarea.SetHeight(e.height - 3) // -1 header, -1 status, -1 padding
+	e.followCursor()
 }
 
 func (e *Editor) Focused() bool {
@@ -144,14 +325,199 @@ func (e *Editor) SetFocused(focused bool) {
 	}
 }
 
-func (e *Editor) save() tea.Cmd {
+// cursorPosition returns the 1-based line and column of the cursor. The
+// textarea tracks the column as a rune index into the logical line, which
+// LineInfo splits into the wrapped row's start plus the offset within it.
+func (e *Editor) cursorPosition() (int, int) {
+	li := e.textarea.LineInfo()
+	return e.textarea.Line() + 1, li.StartColumn + li.ColumnOffset + 1
+}
+
+// gotoLine moves the cursor to the start of a 1-based line, clamped to the
+// buffer, and scrolls the textarea so it is visible
+func (e *Editor) gotoLine(line int) {
+	target := max(0, min(line-1, e.textarea.LineCount()-1))
+	// The textarea only moves one (wrapped) row at a time
+	for e.textarea.Line() > target {
+		e.textarea.CursorUp()
+	}
+	for e.textarea.Line() < target {
+		e.textarea.CursorDown()
+	}
+	e.textarea.CursorStart()
+	// Update repositions the textarea's viewport around the cursor
+	e.textarea, _ = e.textarea.Update(nil)
+	e.followCursor()
+}
+
+// goToTyped moves to the line entered at the go-to-line prompt
+func (e *Editor) goToTyped(typed string) tea.Cmd {
+	// Accept vim-style ":N" as well as a bare number
+	value := strings.TrimPrefix(strings.TrimSpace(typed), ":")
+	line, err := strconv.Atoi(value)
+	if err != nil {
+		e.status = "Invalid line number: " + typed
+		return nil
+	}
+	e.gotoLine(line)
+	e.status = ""
+	return nil
+}
+
+// saveKind says why a save happens, which decides what follows it
+type saveKind int
+
+const (
+	saveAsked   saveKind = iota // ctrl+s, then back to the viewer
+	saveOnLeave                 // auto-save on esc, then back to the viewer
+	saveIdle                    // auto-save after a pause, staying in the editor
+)
+
+// save writes the buffer, first asking before overwriting a file that
+// changed on disk since it was opened. An idle save never asks; it leaves
+// the question for a save the user starts.
+func (e *Editor) save(kind saveKind) tea.Cmd {
+	if question := e.diskChange(); question != "" {
+		if kind == saveIdle {
+			e.status = "Auto-save skipped: the file changed on disk"
+			return nil
+		}
+		return e.ask("overwrite", question, "Save cancelled", func() tea.Cmd { return e.write(kind) })
+	}
+	return e.write(kind)
+}
+
+// ask asks a y/n question before action, running yes if confirmed and
+// otherwise leaving declined on the status line
+func (e *Editor) ask(action, question, declined string, yes func() tea.Cmd) tea.Cmd {
+	return askConfirm(action, question, yes, func() tea.Cmd {
+		e.status = declined
+		return nil
+	})
+}
+
+// reload replaces the buffer with the file on disk, asking first when
+// that throws away edits. The cursor stays on its line.
+func (e *Editor) reload() tea.Cmd {
+	if _, err := os.Stat(e.path); err != nil {
+		e.status = "Cannot reload: " + filepath.Base(e.path) + " was removed or renamed since open (" + errorText(err) + ")"
+		return nil
+	}
+	load := func() tea.Cmd {
+		line, col := e.cursorPosition()
+		e.notice = "Reloaded from disk"
+		return e.Open(e.path, line, col)
+	}
+	if e.modified {
+		return e.ask("discard", "Discard your changes and reload from disk?", "Reload cancelled", load)
+	}
+	return load()
+}
+
+// diskChange compares the file with its state at open, returning the
+// question to ask if it was modified or removed in the meantime
+func (e *Editor) diskChange() string {
+	if !e.existed {
+		return ""
+	}
+	info, err := os.Stat(e.path)
+	if err != nil {
+		return "File was removed since open; save anyway?"
+	}
+	if !info.ModTime().Equal(e.openedModTime) || info.Size() != e.openedSize {
+		if !e.modified {
+			return "File changed on disk since open and you have no edits; overwrite with the old content?"
+		}
+		return "File changed on disk since open; overwrite?"
+	}
+	return ""
+}
+
+// write saves the buffer with the save rules applied. An idle save leaves
+// the buffer as typed, so trimming never pulls text from under the cursor.
+func (e *Editor) write(kind saveKind) tea.Cmd {
+	content := applySaveRules(e.textarea.Value(), e.cfg.SaveRulesFor(e.path))
+	if content != e.textarea.Value() && kind != saveIdle {
+		e.setValueKeepCursor(content)
+	}
+	path, edit := e.path, e.edits
 	return func() tea.Msg {
-		content := e.textarea.Value()
-		err := os.WriteFile(e.path, []byte(content), 0644)
-		return EditorSavedMsg{Path: e.path, Err: err}
+		err := writeFileAtomic(path, []byte(content))
+		return EditorSavedMsg{Path: path, Bytes: len(content), Kind: kind, Edit: edit, Err: err}
 	}
 }
 
+// writeFileAtomic replaces path's content by writing a temporary file
+// beside it and renaming it into place, so a failed save leaves the old
+// file whole. An existing file keeps its permissions, and a symlink is
+// followed rather than replaced.
+func writeFileAtomic(path string, data []byte) error {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	perm := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Chmod(perm)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// setValueKeepCursor replaces the buffer, returning the cursor to the same
+// line and (clamped) column rather than the end where SetValue leaves it
+func (e *Editor) setValueKeepCursor(content string) {
+	row := e.textarea.Line()
+	li := e.textarea.LineInfo()
+	col := li.StartColumn + li.ColumnOffset
+	e.textarea.SetValue(content)
+	e.gotoLine(row + 1)
+	e.textarea.SetCursor(col)
+}
+
+// applySaveRules applies the configured save transforms to content
+func applySaveRules(content string, rules SaveRules) string {
+	eol := "\n"
+	if strings.Contains(content, "\r\n") {
+		eol = "\r\n"
+	}
+	if rules.TrimTrailingWhitespace {
+		lines := strings.Split(content, "\n")
+		for i, line := range lines {
+			cr := strings.HasSuffix(line, "\r")
+			line = strings.TrimRight(strings.TrimSuffix(line, "\r"), " \t")
+			if cr {
+				line += "\r"
+			}
+			lines[i] = line
+		}
+		content = strings.Join(lines, "\n")
+	}
+	if rules.EnsureFinalNewline && content != "" {
+		content = strings.TrimRight(content, "\r\n") + eol
+	}
+	return content
+}
+
 func (e *Editor) cancel() tea.Cmd {
 	return func() tea.Msg {
 		return EditorCancelledMsg{Path: e.path}
@@ -166,14 +532,34 @@ func (e *Editor) centerText(text string) string {
 	return style.Render(text)
 }
 
-// Open prepares the editor to edit a file
-func (e *Editor) Open(path string) tea.Cmd {
+// SetFormatBadge sets whether and how the status line shows the file's
+// encoding and line endings
+func (e *Editor) SetFormatBadge(badge formatBadge) {
+	e.formatBadge = badge
+}
+
+// SetNotice sets a message for the status line of the next file opened
+func (e *Editor) SetNotice(notice string) {
+	e.notice = notice
+}
+
+// Open prepares the editor to edit a file with the cursor on a 1-based
+// line and column; 0 leaves it where the textarea puts it
+func (e *Editor) Open(path string, line, col int) tea.Cmd {
 	e.path = path
 	return func() tea.Msg {
+		if err := checkSpecial(path); err != nil {
+			return EditorOpenMsg{Path: path, Line: line, Col: col, Err: err}
+		}
+		// Stat first so a write racing the read shows up as a change
+		info, _ := os.Stat(path)
 		content, err := os.ReadFile(path)
 		return EditorOpenMsg{
 			Path:    path,
 			Content: string(content),
+			Info:    info,
+			Line:    line,
+			Col:     col,
 			Err:     err,
 		}
 	}
@@ -183,13 +569,26 @@ func (e *Editor) Open(path string) tea.Cmd {
 type EditorOpenMsg struct {
 	Path    string
 	Content string
+	Info    os.FileInfo // nil if the file could not be stat'ed
+	Line    int         // 1-based line to put the cursor on, 0 for none
+	Col     int         // 1-based column on Line, 0 for its start
 	Err     error
 }
 
 // EditorSavedMsg is sent when a file has been saved
 type EditorSavedMsg struct {
+	Path  string
+	Bytes int // written, when Err is nil
+	Kind  saveKind
+	Edit  int // the editor's edit count when the buffer was taken
+	Err   error
+}
+
+// EditorIdleMsg is sent a while after a key was typed in the editor, to
+// auto-save if nothing was typed since
+type EditorIdleMsg struct {
 	Path string
-	Err  error
+	Edit int
 }
 
 // EditorCancelledMsg is sent when editing is cancelled
@@ -203,7 +602,7 @@ func isTextFile(path string) bool {
 	textExts := map[string]bool{
 		".txt": true, ".md": true, ".markdown": true,
 		".go": true, ".py": true, ".js": true, ".ts": true,
-		".json": true, ".yaml": true, ".yml": true, ".toml": true,
+		".json": true, ".ndjson": true, ".jsonl": true, ".yaml": true, ".yml": true, ".toml": true,
 		".html": true, ".css": true, ".xml": true,
 		".sh": true, ".bash": true, ".zsh": true,
 		".c": true, ".h": true, ".cpp": true, ".hpp": true,
@@ -213,5 +612,5 @@ func isTextFile(path string) bool {
 		".gitignore": true, ".dockerignore": true,
 		"": true, // no extension - might be text
 	}
-	return textExts[ext]
+	return textExts[ext] || knownFilenames[filepath.Base(path)] != ""
 }
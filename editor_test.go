@@ -0,0 +1,62 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// TestEditorLeaveAfterMovingDoesNotSave moves the cursor about and leaves
+// with auto-save on leave, which must not write the file, and then checks
+// that typing does make leaving save
+func TestEditorLeaveAfterMovingDoesNotSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	const content = "one\ntwo\nthree\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(path, past, past); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := DefaultConfig().Editor
+	cfg.AutoSave.OnLeave = true
+	e := NewEditor(cfg)
+	e.SetSize(60, 10)
+	e.SetFocused(true)
+	settle(e, e.Open(path, 0, 0))
+	press := func(keys ...tea.KeyType) {
+		for _, key := range keys {
+			_, cmd := e.Update(tea.KeyMsg{Type: key})
+			settle(e, cmd)
+		}
+	}
+
+	press(tea.KeyDown, tea.KeyDown, tea.KeyRight, tea.KeyUp, tea.KeyEnd, tea.KeyPgDown, tea.KeyHome, tea.KeyEsc)
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().Equal(past) {
+		t.Errorf("leaving after only moving wrote the file: modified %v, was %v", info.ModTime(), past)
+	}
+	if e.modified {
+		t.Error("moving the cursor marked the buffer modified")
+	}
+
+	settle(e, e.Open(path, 0, 0))
+	_, cmd := e.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+	settle(e, cmd)
+	press(tea.KeyEsc)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) == content {
+		t.Error("leaving after typing did not save")
+	}
+}
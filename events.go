@@ -0,0 +1,51 @@
+package main
+
+import tea "github.com/charmbracelet/bubbletea"
+
+// EventKind says what an AppEvent reports
+type EventKind int
+
+const (
+	EventSelected EventKind = iota // a file was opened from the nav or a link
+	EventFocus                     // focus moved between nav and viewer
+	EventMode                      // the App entered or left the editor
+)
+
+// AppEvent describes a change a host application embedding the App may
+// want to follow, such as to update a title bar
+type AppEvent struct {
+	Kind  EventKind
+	Path  string // the selected file, for EventSelected
+	Focus Focus  // after the change
+	Mode  Mode   // after the change
+}
+
+// Observe registers fn to be called with each AppEvent. It runs on the
+// event loop, so it must return quickly and not call back into the App.
+func (a *App) Observe(fn func(AppEvent)) {
+	a.observers = append(a.observers, fn)
+}
+
+// notify tells observers what msg changed, given focus and mode from
+// before it was handled
+func (a *App) notify(msg tea.Msg, focus Focus, mode Mode) {
+	if len(a.observers) == 0 {
+		return
+	}
+	var events []AppEvent
+	if msg, ok := msg.(FileSelectedMsg); ok {
+		events = append(events, AppEvent{Kind: EventSelected, Path: msg.Path})
+	}
+	if a.focus != focus {
+		events = append(events, AppEvent{Kind: EventFocus})
+	}
+	if a.mode != mode {
+		events = append(events, AppEvent{Kind: EventMode})
+	}
+	for _, event := range events {
+		event.Focus, event.Mode = a.focus, a.mode
+		for _, fn := range a.observers {
+			fn(event)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// exportStdout is the export target that prints on quit instead of writing
+// a file
+const exportStdout = "-"
+
+// exporter is implemented by viewers whose content can be written out as
+// plain text. read may run off the event loop, so it must not touch the
+// viewer.
+type exporter interface {
+	Export() (what string, read func() ([]byte, error))
+}
+
+// ExportedMsg is sent after viewer content was written out
+type ExportedMsg struct {
+	What string // description of what was exported, for the flash
+	Path string // file written, or exportStdout
+	Data []byte // the content, kept for stdout
+	Err  error
+}
+
+// Status describes the export for the flash line
+func (m ExportedMsg) Status() string {
+	switch {
+	case m.Err != nil:
+		return "Export failed: " + m.Err.Error()
+	case m.Path == exportStdout:
+		return "Exported " + m.What + ", printed on quit"
+	}
+	return "Exported " + m.What + " to " + m.Path
+}
+
+// export asks where to write the current viewer's content, starting from
+// the directory of the file being viewed
+func (a *App) export() tea.Cmd {
+	what, read, ok := a.viewer.Export()
+	if !ok {
+		return nil
+	}
+	dir := ""
+	if a.editPath != "" {
+		dir = filepath.Dir(a.editPath) + string(filepath.Separator)
+	}
+	return askInput("Export "+what+" to (- for stdout on quit): ", dir, func(dest string) tea.Cmd {
+		dest = strings.TrimSpace(dest)
+		if dest == "" || strings.HasSuffix(dest, string(filepath.Separator)) {
+			return nil
+		}
+		write := exportTo(what, dest, read)
+		if dest == exportStdout {
+			return write
+		}
+		if _, err := os.Stat(dest); err == nil {
+			return askConfirm("overwrite", "Overwrite "+dest+"?", func() tea.Cmd { return write }, nil)
+		}
+		return write
+	})
+}
+
+// exportTo reads the content and writes it to dest
+func exportTo(what, dest string, read func() ([]byte, error)) tea.Cmd {
+	return func() tea.Msg {
+		data, err := read()
+		if err == nil && dest != exportStdout {
+			err = writeFileAtomic(dest, data)
+		}
+		return ExportedMsg{What: what, Path: dest, Data: data, Err: err}
+	}
+}
+
+// Export returns the content of the current viewer, reporting false when
+// it has nothing to export
+func (r *ViewerRouter) Export() (string, func() ([]byte, error), bool) {
+	e, ok := r.current.(exporter)
+	if !ok {
+		return "", nil, false
+	}
+	what, read := e.Export()
+	return what, read, read != nil
+}
+
+// Export reads the file as it is on disk
+func (v *TextViewer) Export() (string, func() ([]byte, error)) {
+	if v.path == "" || v.err != nil {
+		return "", nil
+	}
+	path := v.path
+	return filepath.Base(path), func() ([]byte, error) {
+		return readContent(path)
+	}
+}
+
+// Export gives the source in plain mode, and the rendered text, without
+// its styling, otherwise
+func (m *MarkdownViewer) Export() (string, func() ([]byte, error)) {
+	if m.source == "" || m.err != nil {
+		return "", nil
+	}
+	name := filepath.Base(m.path)
+	if m.showingPlain() {
+		source := m.source
+		return name, func() ([]byte, error) {
+			return []byte(source), nil
+		}
+	}
+	text := m.renderedText(false)
+	return "rendered " + name, func() ([]byte, error) {
+		return []byte(text), nil
+	}
+}
+
+// Export re-serializes the value under the cursor, or the whole document
+// on its root
+func (j *JSONViewer) Export() (string, func() ([]byte, error)) {
+	if j.root == nil || j.err != nil {
+		return "", nil
+	}
+	node, what := j.root, filepath.Base(j.path)
+	if visible := j.visibleNodes(); j.cursor < len(visible) && visible[j.cursor] != j.root {
+		node = visible[j.cursor]
+		what = strings.ReplaceAll(nodePath(node), nodePathSep, ".") + " from " + what
+	}
+	data, err := encodeJSON(nodeValue(node), "  ")
+	return what, func() ([]byte, error) {
+		return data, err
+	}
+}
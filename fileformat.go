@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// formatSniffBytes is how much of the start of a file decides its encoding
+const formatSniffBytes = 64 << 10
+
+// fileFormat is the detected encoding and line-ending style of a file
+type fileFormat struct {
+	encoding string // "UTF-8", "UTF-8 BOM", "UTF-16LE", "UTF-16BE", "non-UTF-8" or "binary"
+	eol      string // "LF", "CRLF", "CR", "mixed" or "" with no line breaks
+}
+
+func (f fileFormat) String() string {
+	if f.eol == "" {
+		return f.encoding
+	}
+	return f.encoding + " " + f.eol
+}
+
+// formatScanner detects a fileFormat from a file read in chunks
+type formatScanner struct {
+	head     []byte // the first formatSniffBytes
+	lf, crlf int
+	cr       int  // all carriage returns, including those of CRLF
+	lastCR   bool // the previous chunk ended in a carriage return
+}
+
+func (s *formatScanner) scan(chunk []byte) {
+	if need := formatSniffBytes - len(s.head); need > 0 {
+		s.head = append(s.head, chunk[:min(need, len(chunk))]...)
+	}
+	s.cr += bytes.Count(chunk, []byte{'\r'})
+	s.crlf += bytes.Count(chunk, []byte("\r\n"))
+	if s.lastCR && len(chunk) > 0 && chunk[0] == '\n' {
+		s.crlf++
+	}
+	s.lf += bytes.Count(chunk, []byte{'\n'})
+	if len(chunk) > 0 {
+		s.lastCR = chunk[len(chunk)-1] == '\r'
+	}
+}
+
+func (s *formatScanner) format() fileFormat {
+	var f fileFormat
+	switch {
+	case bytes.HasPrefix(s.head, []byte{0xEF, 0xBB, 0xBF}):
+		f.encoding = "UTF-8 BOM"
+	case bytes.HasPrefix(s.head, []byte{0xFF, 0xFE}):
+		f.encoding = "UTF-16LE"
+	case bytes.HasPrefix(s.head, []byte{0xFE, 0xFF}):
+		f.encoding = "UTF-16BE"
+	case bytes.IndexByte(s.head, 0) >= 0:
+		f.encoding = "binary"
+	case utf8.Valid(trimPartialRune(s.head)):
+		f.encoding = "UTF-8"
+	default:
+		f.encoding = "non-UTF-8"
+	}
+
+	loneLF, loneCR := s.lf-s.crlf, s.cr-s.crlf
+	kinds := 0
+	for _, n := range []int{loneLF, s.crlf, loneCR} {
+		if n > 0 {
+			kinds++
+		}
+	}
+	switch {
+	case kinds > 1:
+		f.eol = "mixed"
+	case s.crlf > 0:
+		f.eol = "CRLF"
+	case loneCR > 0:
+		f.eol = "CR"
+	case loneLF > 0:
+		f.eol = "LF"
+	}
+	return f
+}
+
+// detectFormat detects the format of content held in memory
+func detectFormat(content []byte) fileFormat {
+	var s formatScanner
+	s.scan(content)
+	return s.format()
+}
+
+// binarySniffBytes is how much of a file looksBinary reads
+const binarySniffBytes = 8 << 10
+
+// looksBinary reports whether the start of a regular file holds NUL bytes
+// outside UTF-16 text
+func looksBinary(path string) bool {
+	// Opening a FIFO would wait for a writer, so check before opening
+	if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	buf := make([]byte, binarySniffBytes)
+	n, _ := io.ReadFull(f, buf)
+	return detectFormat(buf[:n]).encoding == "binary"
+}
+
+// trimPartialRune drops an incomplete UTF-8 sequence cut off at the end of
+// b, as a sniffed prefix can end in the middle of one
+func trimPartialRune(b []byte) []byte {
+	for i := 1; i < utf8.UTFMax && i <= len(b); i++ {
+		if utf8.RuneStart(b[len(b)-i]) {
+			if !utf8.FullRune(b[len(b)-i:]) {
+				return b[:len(b)-i]
+			}
+			break
+		}
+	}
+	return b
+}
+
+// formatBadge is how a detected format is shown next to a file name
+type formatBadge struct {
+	show  bool
+	style lipgloss.Style
+}
+
+func newFormatBadge(cfg EncodingConfig) formatBadge {
+	return formatBadge{show: cfg.Show, style: lipgloss.NewStyle().Foreground(lipgloss.Color(cfg.Color))}
+}
+
+// render returns f styled, or "" when the badge is off
+func (b formatBadge) render(f fileFormat) string {
+	if !b.show || f.encoding == "" {
+		return ""
+	}
+	return b.style.Render(f.String())
+}
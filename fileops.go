@@ -0,0 +1,194 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// FileOp identifies a file management operation
+type FileOp int
+
+const (
+	OpDelete FileOp = iota
+	OpCopy
+	OpMove
+	OpCreate
+)
+
+func (o FileOp) String() string {
+	switch o {
+	case OpDelete:
+		return "delete"
+	case OpCopy:
+		return "copy"
+	case OpMove:
+		return "move"
+	case OpCreate:
+		return "create"
+	}
+	return "unknown"
+}
+
+// OpResult is the outcome of an operation on a single path
+type OpResult struct {
+	Path string
+	Err  error
+}
+
+// FileOpDoneMsg is sent when a (possibly batched) file operation finishes
+type FileOpDoneMsg struct {
+	Op      FileOp
+	Results []OpResult
+}
+
+// Summary reports successes and per-item failures on one line
+func (m FileOpDoneMsg) Summary() string {
+	var failed []string
+	for _, r := range m.Results {
+		if r.Err != nil {
+			failed = append(failed, filepath.Base(r.Path)+": "+errorText(r.Err))
+		}
+	}
+	ok := len(m.Results) - len(failed)
+	if len(failed) == 0 {
+		return fmt.Sprintf("%s: %d ok", m.Op, ok)
+	}
+	return fmt.Sprintf("%s: %d ok, %d failed (%s)", m.Op, ok, len(failed), strings.Join(failed, "; "))
+}
+
+// runFileOp applies op to each path in turn, continuing past failures.
+// dest is the target directory for copy and move.
+func runFileOp(op FileOp, paths []string, dest string) tea.Cmd {
+	return func() tea.Msg {
+		results := make([]OpResult, 0, len(paths))
+		for _, p := range paths {
+			var err error
+			switch op {
+			case OpDelete:
+				err = deletePath(p)
+			case OpCopy:
+				err = copyPath(p, dest)
+			case OpMove:
+				err = movePath(p, dest)
+			}
+			results = append(results, OpResult{Path: p, Err: err})
+		}
+		return FileOpDoneMsg{Op: op, Results: results}
+	}
+}
+
+// deletePath removes a file or a directory and everything under it
+func deletePath(path string) error {
+	return os.RemoveAll(path)
+}
+
+// copyPath copies a file or directory tree into destDir, keeping its name
+func copyPath(src, destDir string) error {
+	target := filepath.Join(destDir, filepath.Base(src))
+	if err := checkTarget(src, target); err != nil {
+		return err
+	}
+	return copyTree(src, target)
+}
+
+// movePath moves a file or directory into destDir, falling back to copy and
+// delete when the rename crosses filesystems
+func movePath(src, destDir string) error {
+	target := filepath.Join(destDir, filepath.Base(src))
+	if err := checkTarget(src, target); err != nil {
+		return err
+	}
+	err := os.Rename(src, target)
+	if errors.Is(err, syscall.EXDEV) {
+		if err := copyTree(src, target); err != nil {
+			return err
+		}
+		return os.RemoveAll(src)
+	}
+	return err
+}
+
+// checkTarget refuses to clobber an existing path or copy a directory into
+// itself
+func checkTarget(src, target string) error {
+	if _, err := os.Lstat(target); err == nil {
+		return fmt.Errorf("%s already exists", target)
+	}
+	rel, err := filepath.Rel(src, target)
+	outside := rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator))
+	if err == nil && !outside {
+		return errors.New("cannot copy a directory into itself")
+	}
+	return nil
+}
+
+func copyTree(src, target string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		link, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(link, target)
+
+	case info.IsDir():
+		if err := os.Mkdir(target, info.Mode().Perm()); err != nil {
+			return err
+		}
+		children, err := os.ReadDir(src)
+		if err != nil {
+			return err
+		}
+		for _, c := range children {
+			if err := copyTree(filepath.Join(src, c.Name()), filepath.Join(target, c.Name())); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if kind := specialKind(info.Mode()); kind != "" {
+		return &specialFileError{Path: src, Kind: kind}
+	}
+
+	return copyFile(src, target, info.Mode().Perm())
+}
+
+func copyFile(src, target string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// errorText strips the path from *PathError messages, which the caller
+// already shows alongside
+func errorText(err error) string {
+	var pe *os.PathError
+	if errors.As(err, &pe) {
+		return pe.Err.Error()
+	}
+	return err.Error()
+}
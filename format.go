@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// timeLayout is how absolute times are shown in listings
+const timeLayout = "2006-01-02 15:04"
+
+// formatSize shows a byte count either exactly, with thousands separators,
+// or rounded with a binary unit suffix
+func formatSize(n int64, exact bool) string {
+	if !exact {
+		return humanSize(n)
+	}
+	digits := strconv.FormatInt(n, 10)
+	out := make([]byte, 0, len(digits)+len(digits)/3)
+	for i := range len(digits) {
+		if i > 0 && (len(digits)-i)%3 == 0 && digits[i-1] != '-' {
+			out = append(out, ',')
+		}
+		out = append(out, digits[i])
+	}
+	return string(out)
+}
+
+// truncation says where a name too long to show whole is cut
+type truncation int
+
+const (
+	truncateEnd    truncation = iota // "a-very-long-na…"
+	truncateMiddle                   // "a-very…name.txt", keeping the extension when it fits
+)
+
+// parseTruncation reads the nav.truncate config
+func parseTruncation(s string) (truncation, error) {
+	switch s {
+	case "end":
+		return truncateEnd, nil
+	case "middle":
+		return truncateMiddle, nil
+	}
+	return truncateEnd, fmt.Errorf("must be end or middle, not %q", s)
+}
+
+// shorten cuts s to width cells, marking the cut with an ellipsis
+func (t truncation) shorten(s string, width int) string {
+	w := ansi.StringWidth(s)
+	if w <= width {
+		return s
+	}
+	if t == truncateEnd || width < 3 {
+		return ansi.Truncate(s, width, "…")
+	}
+	tail := (width - 1) / 2
+	// A long extension takes more of the end, leaving a character of the
+	// start before the ellipsis
+	if ext := ansi.StringWidth(filepath.Ext(s)); ext > tail && ext <= width-2 {
+		tail = ext
+	}
+	head := width - 1 - tail
+	return ansi.Truncate(s, head, "") + "…" + ansi.TruncateLeft(s, w-tail, "")
+}
+
+// humanSize formats a byte count with a binary unit suffix
+func humanSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%c", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// formatTime shows t either as a timestamp or relative to now, e.g.
+// "3 hours ago"
+func formatTime(t, now time.Time, relative bool) string {
+	if !relative {
+		return t.Format(timeLayout)
+	}
+	d := now.Sub(t)
+	suffix := " ago"
+	if d < 0 {
+		d, suffix = -d, ""
+	}
+	var text string
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		text = plural(int(d/time.Minute), "min")
+	case d < 24*time.Hour:
+		text = plural(int(d/time.Hour), "hour")
+	case d < 30*24*time.Hour:
+		text = plural(int(d/(24*time.Hour)), "day")
+	case d < 365*24*time.Hour:
+		text = plural(int(d/(30*24*time.Hour)), "month")
+	default:
+		text = plural(int(d/(365*24*time.Hour)), "year")
+	}
+	if suffix == "" {
+		return "in " + text
+	}
+	return text + suffix
+}
+
+// plural formats a count of unit, adding an s when there is not one
+func plural(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Invisibles modes for TextViewer, cycled with i
+const (
+	invisiblesOff    = iota
+	invisiblesShown  // tabs, trailing whitespace and control bytes
+	invisiblesSpaces // as above, and every space
+)
+
+// invisiblesTabWidth matches the terminal's tab stops
+const invisiblesTabWidth = 8
+
+var (
+	glyphStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	trailingStyle = lipgloss.NewStyle().Background(lipgloss.Color("52"))
+)
+
+// showInvisibles renders a line with its whitespace and control bytes as
+// visible glyphs: tabs as an arrow to the next tab stop, spaces as a dot
+// when spaces is set, control bytes as their control pictures (␀, ␍…) and
+// undecodable bytes as \xNN. Trailing whitespace is highlighted. Only the
+// display changes; the file is untouched.
+func showInvisibles(line string, spaces bool) string {
+	body := strings.TrimRight(line, " \t")
+	trailing := line[len(body):]
+
+	var b strings.Builder
+	col := writeInvisibles(&b, body, 0, spaces, glyphStyle)
+	writeInvisibles(&b, trailing, col, true, trailingStyle.Inherit(glyphStyle))
+	return b.String()
+}
+
+// writeInvisibles writes s starting at display column col and returns the
+// column it ends at
+func writeInvisibles(b *strings.Builder, s string, col int, spaces bool, glyph lipgloss.Style) int {
+	for len(s) > 0 {
+		r, size := utf8.DecodeRuneInString(s)
+		var text string
+		switch {
+		case r == utf8.RuneError && size == 1:
+			text = glyph.Render(fmt.Sprintf(`\x%02x`, s[0]))
+			col += 4
+		case r == '\t':
+			n := invisiblesTabWidth - col%invisiblesTabWidth
+			text = glyph.Render("→" + strings.Repeat(" ", n-1))
+			col += n
+		case r == ' ' && spaces:
+			text = glyph.Render("·")
+			col++
+		case r < 0x20:
+			text = glyph.Render(string(rune(0x2400 + r)))
+			col++
+		case r == 0x7f:
+			text = glyph.Render("␡")
+			col++
+		default:
+			text = s[:size]
+			col += lipgloss.Width(text)
+		}
+		b.WriteString(text)
+		s = s[size:]
+	}
+	return col
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"log"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Bounds and step for resizing the nav pane with < and >
+const (
+	minNavRatio  = 0.1
+	maxNavRatio  = 0.7
+	navRatioStep = 0.05
+)
+
+// paneLayout is the split and focus remembered for one directory
+type paneLayout struct {
+	Ratio float64 `json:"ratio"`
+	Focus string  `json:"focus"` // "nav" or "viewer"
+}
+
+// rootedPane is implemented by panes that are rooted at a directory
+type rootedPane interface {
+	Root() string
+}
+
+// SetLayouts sets the remembered layouts and the file they are saved to
+// when changed, and applies the one for dir
+func (a *App) SetLayouts(layouts map[string]paneLayout, path, dir string) {
+	if layouts == nil {
+		layouts = make(map[string]paneLayout)
+	}
+	a.layouts = layouts
+	a.layoutsPath = path
+	a.layoutDir = dir
+	a.applyLayout()
+}
+
+// navWidth is the width of the nav pane at the current split
+func (a *App) navWidth() int {
+	return int(float64(a.width) * a.navRatio)
+}
+
+// resizeNav moves the split by delta and remembers it for the directory
+func (a *App) resizeNav(delta float64) {
+	a.navRatio = min(maxNavRatio, max(minNavRatio, a.navRatio+delta))
+	a.updatePaneSizes()
+	a.saveLayout()
+}
+
+// toggleViewer hides the right pane, giving the nav the full width and
+// focus, or brings it back
+func (a *App) toggleViewer() tea.Cmd {
+	if a.panes == PanesNav {
+		return a.setPanes(PanesBoth)
+	}
+	return a.setPanes(PanesNav)
+}
+
+// toggleZoom gives the focused pane the full width, or goes back to the
+// split, at the ratio it had
+func (a *App) toggleZoom() tea.Cmd {
+	switch {
+	case a.panes != PanesBoth:
+		return a.setPanes(PanesBoth)
+	case a.focus == FocusNav:
+		return a.setPanes(PanesNav)
+	}
+	return a.setPanes(PanesViewer)
+}
+
+// showViewer brings the right pane back if it is hidden
+func (a *App) showViewer() tea.Cmd {
+	if a.panes != PanesNav {
+		return nil
+	}
+	return a.setPanes(PanesBoth)
+}
+
+// setPanes draws the given panes, moving focus to one that is drawn and
+// letting the viewer redo its rendering for its new width
+func (a *App) setPanes(panes Panes) tea.Cmd {
+	a.panes = panes
+	a.setFocus(a.focus)
+	a.updatePaneSizes()
+	if panes == PanesNav {
+		return nil
+	}
+	return a.viewer.Rerender()
+}
+
+// followRoot switches to the layout of the nav's root after it changes,
+// keeping the current one for directories without a remembered layout
+func (a *App) followRoot() {
+	r, ok := a.nav.(rootedPane)
+	if !ok || r.Root() == a.navRoot {
+		return
+	}
+	a.navRoot = r.Root()
+	a.layoutDir = a.navRoot
+	a.applyLayout()
+}
+
+func (a *App) applyLayout() {
+	layout, ok := a.layouts[a.layoutDir]
+	if !ok {
+		return
+	}
+	if layout.Ratio >= minNavRatio && layout.Ratio <= maxNavRatio {
+		a.navRatio = layout.Ratio
+		a.updatePaneSizes()
+	}
+	if a.mode != ModeEditor {
+		focus := FocusNav
+		if layout.Focus == FocusViewer.String() {
+			focus = FocusViewer
+		}
+		a.setFocus(focus)
+	}
+}
+
+// saveLayout records the current split and focus for the directory
+func (a *App) saveLayout() {
+	if a.layouts == nil || a.layoutDir == "" {
+		return
+	}
+	a.layouts[a.layoutDir] = paneLayout{Ratio: a.navRatio, Focus: a.focus.String()}
+	if a.layoutsPath != "" {
+		if err := saveJSON(a.layoutsPath, a.layouts); err != nil {
+			log.Printf("saving layouts: %v", err)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"syscall"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// transientErrors are failures a retry may get past, such as a network
+// mount dropping out for a moment
+var transientErrors = []error{syscall.EIO, syscall.ETIMEDOUT, syscall.EAGAIN, syscall.ESTALE, syscall.EINTR}
+
+// isTransient reports whether err looks like a passing failure rather than
+// one that will be the same next time
+func isTransient(err error) bool {
+	if os.IsTimeout(err) {
+		return true
+	}
+	for _, target := range transientErrors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// specialFileError refuses to read a FIFO, socket or device, where a read
+// can block forever or never end
+type specialFileError struct {
+	Path string
+	Kind string // from specialKind
+}
+
+func (e *specialFileError) Error() string {
+	return e.Path + ": special file: " + e.Kind
+}
+
+// specialKind names the kind of special file mode is, or returns "" for
+// regular files and directories
+func specialKind(mode fs.FileMode) string {
+	switch {
+	case mode&fs.ModeNamedPipe != 0:
+		return "fifo"
+	case mode&fs.ModeSocket != 0:
+		return "socket"
+	case mode&fs.ModeCharDevice != 0:
+		return "character device"
+	case mode&fs.ModeDevice != 0:
+		return "block device"
+	case mode&fs.ModeIrregular != 0:
+		return "irregular file"
+	}
+	return ""
+}
+
+// checkSpecial returns a specialFileError when path, followed through
+// links, is a special file. Stat never blocks, where opening a FIFO does.
+// A failed stat is left for the read to report.
+func checkSpecial(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil
+	}
+	if kind := specialKind(info.Mode()); kind != "" {
+		return &specialFileError{Path: path, Kind: kind}
+	}
+	return nil
+}
+
+// loadErrorText describes a failed load for a viewer's error screen,
+// saying whether trying again is likely to help
+func loadErrorText(err error) string {
+	var se *specialFileError
+	if errors.As(err, &se) {
+		return "Special file: " + se.Kind + "\n\n" + se.Path + "\nIt is not read, as reading it could wait forever."
+	}
+	path, text := "", err.Error()
+	var pe *fs.PathError
+	if errors.As(err, &pe) {
+		path, text = pe.Path, pe.Path+": "+errorText(err)
+	}
+	switch {
+	case isTransient(err):
+		return "Temporary error, try again: " + text + "\n\nr: retry"
+	case errors.Is(err, fs.ErrNotExist) && path != "":
+		return "No longer exists: " + path + "\n\nIt was deleted or renamed since it was listed."
+	case errors.Is(err, fs.ErrPermission) && path != "":
+		return "Permission denied: " + path
+	}
+	return "Error: " + text
+}
+
+// FileGoneMsg is sent when a viewer found its file deleted by the time it
+// was read, so the nav can drop the stale entry
+type FileGoneMsg struct {
+	Path string
+}
+
+// fileGone returns a command sending FileGoneMsg when err says path does
+// not exist, and nil otherwise
+func fileGone(path string, err error) tea.Cmd {
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return func() tea.Msg {
+		return FileGoneMsg{Path: path}
+	}
+}
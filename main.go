@@ -1,21 +1,163 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"io/fs"
 	"os"
+	"strconv"
+	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/term"
 )
 
+// nonTTYSize is the frame printed when the output is not a terminal and
+// non_tty is "render"
+const nonTTYSize = "80x24"
+
 func main() {
-	p := tea.NewProgram(
-		NewApp(),
+	pickDir := flag.Bool("pick-dir", false, "only show directories; press s to choose one")
+	printPath := flag.Bool("print-path", false, "print the chosen path to stdout on exit")
+	configPath := flag.String("config", "", "config file (default: dmc-nav/config.json in the user config dir)")
+	expand := flag.String("expand", "", "how much of the tree opens at startup: cwd, none or a number of levels (default from config)")
+	render := flag.String("render", "", "print one frame of WIDTHxHEIGHT to stdout and exit, without a terminal")
+	keys := flag.String("keys", "", "with --render, space-separated keys to press first, e.g. \"j j enter\"")
+	flag.Parse()
+
+	if *configPath == "" {
+		*configPath, _ = ConfigPath()
+	}
+	cfg, err := LoadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: config: %v\n", err)
+		os.Exit(1)
+	}
+	if *expand != "" {
+		if _, err := parseStartupExpand(*expand); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --expand: %v\n", err)
+			os.Exit(1)
+		}
+		cfg.Nav.StartupExpand = *expand
+	}
+
+	// The alt screen is drawn to stdout, or stderr with --print-path, and
+	// anything else but a terminal there gets escape codes, not a UI. With
+	// stdout piped, such as to take what is exported to "-", it is drawn
+	// on the terminal itself.
+	out := os.Stdout
+	if *printPath {
+		out = os.Stderr
+	}
+	if *render == "" && !*printPath && !term.IsTerminal(out.Fd()) {
+		if tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0); err == nil {
+			defer tty.Close()
+			out = tty
+		}
+	}
+	if *render == "" && !term.IsTerminal(out.Fd()) {
+		if cfg.NonTTY != "render" {
+			fmt.Fprintln(os.Stderr, "Error: dmc-nav requires an interactive terminal; use --render for headless output")
+			os.Exit(1)
+		}
+		*render = nonTTYSize
+	}
+	if *render == "" && out != os.Stdout {
+		// Colors are for where the UI is drawn, set before any style is
+		// made
+		lipgloss.SetDefaultRenderer(lipgloss.NewRenderer(out))
+	}
+
+	defer openLog().Close()
+
+	opts := Options{PickDir: *pickDir, Headless: *render != ""}
+	if flag.NArg() > 0 {
+		opts.Path, opts.Line, opts.Col = parsePathArg(flag.Arg(0))
+		if err := checkPathArg(opts.Path); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	app := NewApp(cfg, opts)
+
+	if *render != "" {
+		width, height, err := parseSize(*render)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --render: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(renderFrame(app, width, height, parseKeys(*keys)))
+		return
+	}
+
+	programOpts := []tea.ProgramOption{
 		tea.WithAltScreen(),
 		tea.WithMouseCellMotion(),
-	)
+	}
+	if out != os.Stdout {
+		// Keep stdout clean for the path, so $(dmc-nav --print-path)
+		// works, or for what is exported to it
+		programOpts = append(programOpts, tea.WithOutput(out))
+	}
+	p := tea.NewProgram(app, programOpts...)
 
-	if _, err := p.Run(); err != nil {
+	_, err = p.Run()
+	app.Close()
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
+
+	if *printPath && app.ChosenPath() != "" {
+		fmt.Println(app.ChosenPath())
+	}
+	os.Stdout.Write(app.Stdout())
+}
+
+// parsePathArg splits a "path:line" or "path:line:col" argument, as printed
+// by grep and compilers. A path that exists as given is taken whole, so
+// names containing colons still open.
+func parsePathArg(arg string) (string, int, int) {
+	if _, err := os.Stat(arg); err == nil {
+		return arg, 0, 0
+	}
+	path, line, col := arg, 0, 0
+	if rest, n, ok := cutNumber(path); ok {
+		path, line = rest, n
+		if rest, n, ok := cutNumber(path); ok {
+			path, line, col = rest, n, line
+		}
+	}
+	return path, line, col
+}
+
+// checkPathArg makes sure the path argument is something that can be
+// opened: a directory to root the tree at, or a file to view
+func checkPathArg(path string) error {
+	info, err := os.Stat(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return fmt.Errorf("%s: no such file or directory", path)
+	case err != nil:
+		return err
+	case !info.IsDir() && !info.Mode().IsRegular():
+		return fmt.Errorf("%s: not a file or directory, so there is nothing to show", path)
+	}
+	return nil
+}
+
+// cutNumber splits a trailing ":N" off s
+func cutNumber(s string) (string, int, bool) {
+	i := strings.LastIndexByte(s, ':')
+	if i < 0 {
+		return s, 0, false
+	}
+	n, err := strconv.Atoi(s[i+1:])
+	if err != nil {
+		return s, 0, false
+	}
+	return s[:i], n, true
 }
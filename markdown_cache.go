@@ -0,0 +1,101 @@
+package main
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// defaultMarkdownCacheSize is how many renderings are kept unless
+// configured
+const defaultMarkdownCacheSize = 32
+
+// markdownCacheBytes bounds the rendered text a renderCache holds, however
+// many entries it is allowed, so a few huge documents cannot pile up
+const markdownCacheBytes = 16 << 20
+
+// renderKey identifies a glamour rendering: the file as it was on disk,
+// and how it was rendered
+type renderKey struct {
+	path    string
+	modTime time.Time
+	size    int // bytes of source
+	width   int
+	style   string
+}
+
+type renderEntry struct {
+	key      renderKey
+	rendered string
+}
+
+// renderCache keeps the most recently used glamour renderings, so going
+// back to a document or to an earlier pane width skips rendering. It is
+// used from render commands, so it locks.
+type renderCache struct {
+	mu      sync.Mutex
+	max     int // entries kept
+	bytes   int
+	order   *list.List // of *renderEntry, most recently used first
+	entries map[renderKey]*list.Element
+}
+
+// newRenderCache returns a cache of up to size renderings, or nil, which
+// renders every time, for 0
+func newRenderCache(size int) *renderCache {
+	if size <= 0 {
+		return nil
+	}
+	return &renderCache{max: size, order: list.New(), entries: make(map[renderKey]*list.Element)}
+}
+
+// glamour returns a renderer for the file at path as of modTime that
+// looks in the cache first
+func (c *renderCache) glamour(path string, modTime time.Time) func(source string, width int) (string, error) {
+	return func(source string, width int) (string, error) {
+		if c == nil || modTime.IsZero() {
+			return renderGlamour(source, width)
+		}
+		key := renderKey{path: path, modTime: modTime, size: len(source), width: width, style: glamourStyle}
+		if rendered, ok := c.get(key); ok {
+			return rendered, nil
+		}
+		rendered, err := renderGlamour(source, width)
+		if err == nil {
+			c.put(key, rendered)
+		}
+		return rendered, err
+	}
+}
+
+func (c *renderCache) get(key renderKey) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return "", false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*renderEntry).rendered, true
+}
+
+func (c *renderCache) put(key renderKey, rendered string) {
+	if len(rendered) > markdownCacheBytes {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; ok {
+		return
+	}
+	c.entries[key] = c.order.PushFront(&renderEntry{key: key, rendered: rendered})
+	c.bytes += len(rendered)
+	// Evict the least recently used past either bound
+	for c.order.Len() > c.max || c.bytes > markdownCacheBytes {
+		oldest := c.order.Back()
+		entry := oldest.Value.(*renderEntry)
+		c.order.Remove(oldest)
+		delete(c.entries, entry.key)
+		c.bytes -= len(entry.rendered)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// caseMode decides how searches treat letter case
+type caseMode int
+
+const (
+	caseSmart       caseMode = iota // ignore case unless the query has a capital
+	caseSensitive                   // always match case
+	caseInsensitive                 // never match case
+)
+
+var caseModeNames = [...]string{"smart", "sensitive", "insensitive"}
+
+func (m caseMode) String() string {
+	return caseModeNames[m]
+}
+
+// parseCaseMode reads a case mode by name
+func parseCaseMode(name string) (caseMode, bool) {
+	for i, n := range caseModeNames {
+		if n == name {
+			return caseMode(i), true
+		}
+	}
+	return caseSmart, false
+}
+
+// next cycles to the following mode, for the override toggle
+func (m caseMode) next() caseMode {
+	return (m + 1) % caseMode(len(caseModeNames))
+}
+
+// ignoreCase reports whether query matches regardless of case: as vim's
+// smartcase and ripgrep's --smart-case, a query with no capital letters
+// ignores case unless the mode says otherwise
+func (m caseMode) ignoreCase(query string) bool {
+	switch m {
+	case caseSensitive:
+		return false
+	case caseInsensitive:
+		return true
+	}
+	return !strings.ContainsFunc(query, unicode.IsUpper)
+}
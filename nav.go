@@ -1,18 +1,38 @@
 package main
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
 )
 
 // FileSelectedMsg is sent when a file is selected in the nav pane
 type FileSelectedMsg struct {
 	Path string
+	// Via is the symlink Path was reached through, if any
+	Via string
+	// Outside is set when a symlink resolved outside the nav root
+	Outside bool
+	// Line and Col position the view and editor, 1-based; 0 for the top
+	Line int
+	Col  int
+	// Focus moves focus to the viewer once the file is open
+	Focus bool
+}
+
+// DirChosenMsg is sent when a directory is confirmed in picker mode
+type DirChosenMsg struct {
+	Path string
 }
 
 // FileEntry represents a file or directory in the tree
@@ -22,6 +42,12 @@ type FileEntry struct {
 	IsDir    bool
 	Expanded bool
 	Depth    int
+	Link     string    // symlink target as written, "" if not a link
+	Broken   bool      // symlink whose target does not exist
+	Special  string    // kind of a FIFO, socket or device, from specialKind
+	Loading  bool      // expanded directory whose listing is being read
+	Badge    string    // short note shown at the right, e.g. "12 ln"
+	ModTime  time.Time // of a directory, read with badges to size it
 }
 
 // NavPane is the file tree navigation component
@@ -30,52 +56,244 @@ type NavPane struct {
 	height  int
 	focused bool
 
-	root     string          // root directory path
-	entries  []FileEntry     // flattened visible entries
-	expanded map[string]bool // tracks which directories are expanded
-	cursor   int             // current selection index
-	offset   int             // scroll offset for viewport
+	root          string          // root directory path
+	entries       []FileEntry     // flattened visible entries
+	expanded      map[string]bool // tracks which directories are expanded
+	listings      map[string]dirListing
+	loading       map[string]bool // directories being read in the background
+	want          string          // path to select once it has been read
+	expandBelow   map[string]int  // directories to open the children of once read, with the levels left
+	cursor        int             // current selection index
+	offset        int             // scroll offset for viewport
+	dirsOnly      bool            // picker mode: hide files, enter descends
+	hide          []string        // glob patterns for names left out of the tree
+	scrollOff     int             // rows of context kept around the cursor
+	cursorStyle   cursorStyle
+	scrollbar     scrollbarStyle
+	order         sortOrder
+	wrap          bool // j and k wrap around at the ends
+	leftCollapses bool // h and left collapse the parent; backspace re-roots
+	enterDescends bool // enter re-roots at a directory instead of expanding it
+	focusOnOpen   bool // files opened with enter take focus to the viewer
+	truncate      truncation
+	badges        bool     // read and show entry badges
+	caseMode      caseMode // how the finder treats letter case
+	badgeStyle    lipgloss.Style
+	showHidden    bool                 // show everything, ignoring hide
+	selected      map[string]bool      // multi-selection for batch operations
+	favorites     []string             // pinned directories shown above the tree
+	favoritesPath string               // where favorites persist, "" to keep in memory
+	templateDir   string               // templates for new files, by extension
+	editing       string               // file open in the editor, marked in the tree
+	finder        *finder              // file finder shown instead of the tree
+	finderWalks   int                  // numbers walks so a cancelled one's results are ignored
+	columns       bool                 // miller columns instead of the tree
+	colDir        string               // directory of the active column
+	jumping       bool                 // quick-jump labels shown over entries
+	jumpTyped     string               // label characters typed so far
+	typing        bool                 // type-ahead is reading a name
+	typed         string               // start of the name typed so far
+	typedSeq      int                  // keystrokes typed, to tell idle timers apart
+	typedMiss     bool                 // no entry starts with typed
+	dirSizes      map[string]dirSize   // finished size walks, by directory
+	badgeWalks    map[string]badgeWalk // directories being sized for badges
+	badgeWalkSeq  int                  // numbers badge walks, so stale results are told apart
+	sizing        string               // directory being sized, "" when idle
+	exactSizes    bool                 // directory sizes as byte counts instead of rounded
+	spinner       spinner.Model        // turns in the footer while sizing
+	status        string               // result of the last operation
 }
 
 func NewNavPane(root string) *NavPane {
+	// A file roots the tree at its directory
+	if info, err := os.Stat(root); err == nil && !info.IsDir() {
+		root = filepath.Dir(root)
+	}
 	n := &NavPane{
-		root:     root,
-		expanded: make(map[string]bool),
-		cursor:   0,
+		root:        root,
+		expanded:    make(map[string]bool),
+		listings:    make(map[string]dirListing),
+		expandBelow: make(map[string]int),
+		loading:     make(map[string]bool),
+		selected:    make(map[string]bool),
+		dirSizes:    make(map[string]dirSize),
+		badgeWalks:  make(map[string]badgeWalk),
+		cursor:      0,
+
+		cursorStyle: defaultCursor,
+		scrollbar:   defaultScrollbar,
+		order:       defaultSortOrder,
 	}
-	n.loadEntries()
 	return n
 }
 
+// Init starts reading the root and any directories expanded before the
+// program started
 func (n *NavPane) Init() tea.Cmd {
-	return nil
+	return n.loadEntries()
 }
 
 func (n *NavPane) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
-	if !n.focused {
-		return n, nil
-	}
+	_, cmd := n.update(msg)
+	// Whatever changed the entries in view, their sizes follow
+	return n, tea.Batch(cmd, n.sizeBadgeDirs())
+}
 
+func (n *NavPane) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
+	case FileOpDoneMsg:
+		n.status = msg.Summary()
+		n.selected = make(map[string]bool)
+		if msg.Op == OpCreate && msg.Results[0].Err == nil {
+			return n, tea.Batch(n.refresh(), n.ExpandToPath(msg.Results[0].Path))
+		}
+		return n, n.refresh()
+
+	case DirLoadedMsg:
+		return n, n.dirLoaded(msg)
+
+	case ExternalOpenedMsg:
+		n.status = msg.Status()
+
+	case FinderBatchMsg:
+		return n, n.finderBatch(msg)
+
+	case FileGoneMsg:
+		return n, n.refresh()
+
+	case DirSizeMsg:
+		n.dirSizeDone(msg)
+
+	case TypeAheadIdleMsg:
+		n.typeAheadIdle(msg)
+
+	case spinner.TickMsg:
+		return n, n.tickSpinner(msg)
+
 	case tea.KeyMsg:
+		if !n.focused {
+			return n, nil
+		}
+		if n.finder != nil {
+			return n, n.updateFinder(msg)
+		}
+		if n.jumping {
+			return n, n.updateJump(msg)
+		}
+		if n.typing {
+			if cmd, ok := n.updateTypeAhead(msg); ok {
+				return n, cmd
+			}
+		}
+		n.status = ""
+		if n.columns {
+			if cmd, ok := n.updateColumns(msg); ok {
+				return n, cmd
+			}
+		}
+
 		switch msg.String() {
 		case "j", "down":
-			n.moveCursor(1)
+			n.step(1)
 		case "k", "up":
-			n.moveCursor(-1)
+			n.step(-1)
 		case "g":
 			n.cursor = 0
 			n.offset = 0
 		case "G":
 			n.cursor = len(n.entries) - 1
 			n.adjustOffset()
+		case "}":
+			n.stepSibling(1)
+		case "{":
+			n.stepSibling(-1)
 		case "enter", "l", "right":
 			cmd := n.toggleOrOpen()
 			if cmd != nil {
 				return n, cmd
 			}
-		case "h", "backspace", "left":
-			n.collapseOrParent()
+		case "z":
+			return n, n.toggleExpanded()
+		case "L":
+			// Open the entry, a directory as a listing, and read it in
+			// the viewer
+			return n, n.openEntry(true)
+		case "h", "left":
+			if n.leftCollapses {
+				return n, n.collapseParent()
+			}
+			return n, n.collapseOrParent()
+		case "backspace":
+			if n.leftCollapses {
+				return n, n.collapseOrParent()
+			}
+			return n, n.collapseParent()
+		case "s":
+			if n.dirsOnly {
+				root := n.root
+				return n, func() tea.Msg {
+					return DirChosenMsg{Path: root}
+				}
+			}
+		case "f":
+			n.startJump()
+		case "t":
+			return n, n.startTypeAhead()
+		case "c":
+			if !n.dirsOnly {
+				return n, n.toggleColumns()
+			}
+		case "o":
+			// Show the directory as a detailed listing in the viewer
+			if n.cursor < len(n.entries) && n.entries[n.cursor].IsDir {
+				path := n.entries[n.cursor].Path
+				return n, func() tea.Msg {
+					return FileSelectedMsg{Path: path}
+				}
+			}
+		case "/":
+			if !n.dirsOnly {
+				return n, n.openFinder()
+			}
+		case ".":
+			n.showHidden = !n.showHidden
+			current := n.SelectedPath()
+			cmd := n.loadEntries()
+			n.selectPath(current)
+			n.adjustOffset()
+			if n.showHidden {
+				n.status = "Showing hidden files"
+			}
+			return n, cmd
+		case "i":
+			return n, n.toggleBadges()
+		case "p":
+			n.togglePin()
+		case "'":
+			return n, n.jumpFavorite(1)
+		case "\"":
+			return n, n.jumpFavorite(-1)
+		case " ":
+			n.toggleSelected()
+		case "esc":
+			n.selected = make(map[string]bool)
+		case "D":
+			return n, n.startOp(OpDelete)
+		case "C":
+			return n, n.startOp(OpCopy)
+		case "M":
+			return n, n.startOp(OpMove)
+		case "N":
+			return n, n.startCreate()
+		case "S":
+			return n, n.startDirSize()
+		case "~":
+			return n, n.goToRepoRoot()
+		case "O":
+			// Hand the entry to the OS for files the panes can't show
+			if path := n.SelectedPath(); path != "" {
+				return n, openExternal(path)
+			}
 		}
 	}
 
@@ -83,19 +301,44 @@ func (n *NavPane) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (n *NavPane) View() string {
+	if n.finder != nil {
+		return n.viewFinder()
+	}
+	if n.columns {
+		return n.viewColumns()
+	}
 	if len(n.entries) == 0 {
+		if n.loading[n.root] {
+			return "Loading…"
+		}
+		if err := n.listings[n.root].err; err != nil {
+			return "Cannot read directory: " + errorText(err)
+		}
+		if hidden := n.hiddenCount(n.root); hidden > 0 {
+			return "(" + plural(hidden, "hidden item") + " — press . to show)"
+		}
+		if n.dirsOnly && len(n.listings[n.root].entries) > 0 {
+			return "No subdirectories"
+		}
 		return "Empty directory"
 	}
 
 	var lines []string
-	visibleHeight := n.height - 2 // leave room for header/footer
+	visibleHeight := n.treeHeight()
 
-	// Header showing current directory
+	// Header showing current directory (in full when picking, since the
+	// root is what gets chosen)
+	title := filepath.Base(n.root)
+	if n.dirsOnly {
+		title = n.root
+	}
 	header := lipgloss.NewStyle().
 		Bold(true).
 		Foreground(lipgloss.Color("12")).
-		Render(filepath.Base(n.root))
+		Render(title)
 	lines = append(lines, header)
+	lines = append(lines, n.renderFavorites()...)
+	treeTop := len(lines)
 
 	// File entries
 	end := n.offset + visibleHeight
@@ -106,17 +349,62 @@ func (n *NavPane) View() string {
 	for i := n.offset; i < end; i++ {
 		entry := n.entries[i]
 		line := n.renderEntry(entry, i == n.cursor)
+		if n.jumping {
+			line = n.jumpLabel(line, i)
+		}
 		lines = append(lines, line)
 	}
 
-	// Pad to full height to pin content to top
-	for len(lines) < n.height {
+	// Pad to full height to pin content to top, keeping the last line for
+	// the footer
+	for len(lines) < n.height-1 {
 		lines = append(lines, "")
 	}
+	n.scrollbar.draw(lines, treeTop, visibleHeight, n.width, n.offset, len(n.entries))
+	lines = append(lines, n.footer())
 
 	return strings.Join(lines, "\n")
 }
 
+// footer renders the jump prompt, the last operation's result, a running
+// size walk, or the selection count
+func (n *NavPane) footer() string {
+	style := lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
+	if n.jumping {
+		return style.Render("Jump to: " + n.jumpTyped + " (esc to cancel)")
+	}
+	if n.typing {
+		if n.typedMiss {
+			return style.Render("Go to name: " + n.typed + " (no match)")
+		}
+		return style.Render("Go to name: " + n.typed)
+	}
+	if n.status != "" {
+		return style.Render(n.status)
+	}
+	if n.sizing != "" {
+		return style.Render(n.spinner.View() + " Sizing " + filepath.Base(n.sizing) + "…")
+	}
+	if len(n.selected) > 0 {
+		return style.Render(fmt.Sprintf("%d selected", len(n.selected)))
+	}
+	// A name cut short in the tree is shown whole
+	if n.cursor < len(n.entries) && !n.columns {
+		if entry := n.entries[n.cursor]; entry.Name != "" {
+			if name, _ := n.fitEntry(entry); name != entry.Name {
+				return style.Render(entry.Name)
+			}
+		}
+	}
+	return ""
+}
+
+// CapturingInput reports whether the pane is reading a prompt answer, in
+// which case keys must reach it before any global binding
+func (n *NavPane) CapturingInput() bool {
+	return n.finder != nil || n.jumping || n.typing
+}
+
 func (n *NavPane) SetSize(width, height int) {
 	n.width = width
 	n.height = height
@@ -138,17 +426,128 @@ func (n *NavPane) SelectedPath() string {
 	return ""
 }
 
+// Root returns the directory the tree is rooted at
+func (n *NavPane) Root() string {
+	return n.root
+}
+
+// SetDirsOnly switches directory picker mode, where files are hidden and
+// enter makes the selected directory the new root
+func (n *NavPane) SetDirsOnly(dirsOnly bool) {
+	n.dirsOnly = dirsOnly
+	n.flatten()
+}
+
+// SetHide sets the glob patterns for names left out of the tree and finder
+func (n *NavPane) SetHide(patterns []string) {
+	n.hide = patterns
+	n.flatten()
+}
+
+// SetExactSizes sets whether directory sizes are shown as byte counts
+// rather than rounded
+func (n *NavPane) SetExactSizes(exact bool) {
+	n.exactSizes = exact
+}
+
+// SetScrollOff sets the rows of context kept above and below the cursor
+func (n *NavPane) SetScrollOff(lines int) {
+	n.scrollOff = lines
+}
+
+// SetTruncation sets where names too long for the pane are cut
+func (n *NavPane) SetTruncation(t truncation) {
+	n.truncate = t
+}
+
+// SetEnterDescends sets whether enter re-roots the tree at a directory,
+// leaving z to expand and collapse it
+func (n *NavPane) SetEnterDescends(descends bool) {
+	n.enterDescends = descends
+}
+
+// SetFocusOnOpen sets whether opening a file with enter moves focus to
+// the viewer
+func (n *NavPane) SetFocusOnOpen(focus bool) {
+	n.focusOnOpen = focus
+}
+
+// SetLeftCollapses sets whether h and left collapse up the tree, leaving
+// re-rooting to backspace, or the other way round
+func (n *NavPane) SetLeftCollapses(collapses bool) {
+	n.leftCollapses = collapses
+}
+
+// SetWrapAround sets whether j and k wrap around at the ends of the list
+func (n *NavPane) SetWrapAround(wrap bool) {
+	n.wrap = wrap
+}
+
+// SetCaseMode sets how the finder matches letter case
+func (n *NavPane) SetCaseMode(mode caseMode) {
+	n.caseMode = mode
+}
+
+// SetScrollbar sets how the tree's scrollbar is drawn
+func (n *NavPane) SetScrollbar(style scrollbarStyle) {
+	n.scrollbar = style
+}
+
+// SetEditing marks the file open in the editor, "" for none
+func (n *NavPane) SetEditing(path string) {
+	n.editing = path
+}
+
+// SetSortOrder sets how entries are ordered in the tree
+func (n *NavPane) SetSortOrder(order sortOrder) {
+	n.order = order
+}
+
+// SetCursorStyle sets how the entry under the cursor is drawn
+func (n *NavPane) SetCursorStyle(style cursorStyle) {
+	n.cursorStyle = style
+}
+
 // PinTop scrolls the view to show root at top
 func (n *NavPane) PinTop() {
 	n.offset = 0
 }
 
+// expandToCwd is the startup expansion that reveals the working directory
+const expandToCwd = -1
+
+// parseStartupExpand reads a startup expansion: "cwd" as expandToCwd,
+// "none" as 0, or a number of levels below the root
+func parseStartupExpand(s string) (int, error) {
+	switch s {
+	case "cwd":
+		return expandToCwd, nil
+	case "none":
+		return 0, nil
+	}
+	levels, err := strconv.Atoi(s)
+	if err != nil || levels < 0 {
+		return 0, fmt.Errorf("must be cwd, none or a number of levels, not %q", s)
+	}
+	return levels, nil
+}
+
+// ExpandLevels opens every directory down to levels below the root as
+// their listings arrive. Only directories it opened are descended into,
+// so it stops once the startup tree is built.
+func (n *NavPane) ExpandLevels(levels int) {
+	if levels > 0 {
+		n.expandBelow[n.root] = levels
+	}
+}
+
 // ExpandToPath expands all directories along the path from root to target
-func (n *NavPane) ExpandToPath(target string) {
+// and moves the cursor to it once they have been read
+func (n *NavPane) ExpandToPath(target string) tea.Cmd {
 	// Get relative path from root to target
 	rel, err := filepath.Rel(n.root, target)
 	if err != nil || strings.HasPrefix(rel, "..") {
-		return // target is not under root
+		return nil // target is not under root
 	}
 
 	// Expand each directory along the path
@@ -168,112 +567,392 @@ func (n *NavPane) ExpandToPath(target string) {
 		}
 	}
 
-	// Reload entries with new expanded state
-	n.loadEntries()
+	if n.columns {
+		n.colDir = filepath.Dir(target)
+		if target == n.root {
+			n.colDir = n.root
+		}
+	}
+	cmd := n.loadEntries()
+	if n.selectPath(target) {
+		n.adjustOffset()
+	} else {
+		n.want = target
+	}
+	return cmd
+}
 
-	// Position cursor on target (or nearest parent if target not found)
-	for i, e := range n.entries {
-		if e.Path == target {
-			n.cursor = i
-			n.adjustOffset()
-			return
+// DirLoadedMsg is sent when a directory listing has been read
+type DirLoadedMsg struct {
+	Dir     string
+	Entries []FileEntry // sorted children, Depth 0
+	Err     error
+}
+
+// dirListing is the cached content of one directory
+type dirListing struct {
+	entries []FileEntry
+	err     error
+}
+
+// loadEntries rebuilds the visible tree from cached listings and returns
+// a command reading any expanded directory not yet listed. Reads happen off
+// the event loop so a slow disk never freezes input.
+func (n *NavPane) loadEntries() tea.Cmd {
+	n.flatten()
+	var cmds []tea.Cmd
+	for _, dir := range n.unlisted() {
+		n.loading[dir] = true
+		cmds = append(cmds, readDirCmd(dir, n.badges, n.order))
+	}
+	n.flatten() // show the loading markers
+	return tea.Batch(cmds...)
+}
+
+// refresh re-reads every listed directory still in view. The cached
+// listings stay on screen until the new ones arrive.
+func (n *NavPane) refresh() tea.Cmd {
+	var cmds []tea.Cmd
+	dirs := []string{n.root}
+	if n.columns {
+		dirs = append(dirs, n.colDir)
+		if preview := n.previewDir(); preview != "" {
+			dirs = append(dirs, preview)
 		}
 	}
-	// If exact target not found, try to find closest parent
-	for i := len(n.entries) - 1; i >= 0; i-- {
-		if strings.HasPrefix(target, n.entries[i].Path) {
+	for _, e := range n.entries {
+		if e.IsDir && e.Expanded {
+			dirs = append(dirs, e.Path)
+		}
+	}
+	for _, dir := range dirs {
+		if !n.loading[dir] {
+			n.loading[dir] = true
+			cmds = append(cmds, readDirCmd(dir, n.badges, n.order))
+		}
+	}
+	return tea.Batch(cmds...)
+}
+
+// unlisted returns the root and expanded directories in view that have no
+// listing and are not already being read
+func (n *NavPane) unlisted() []string {
+	wanted := []string{n.root}
+	if n.columns {
+		wanted = append(wanted, n.colDir, n.previewDir())
+	}
+	for _, e := range n.entries {
+		if e.Expanded {
+			wanted = append(wanted, e.Path)
+		}
+	}
+
+	var dirs []string
+	for _, dir := range wanted {
+		if _, ok := n.listings[dir]; dir != "" && !ok && !n.loading[dir] && !slices.Contains(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// dirLoaded stores a listing and rebuilds the tree, keeping the cursor on
+// the same path, or moving it to a path waiting to be revealed
+func (n *NavPane) dirLoaded(msg DirLoadedMsg) tea.Cmd {
+	delete(n.loading, msg.Dir)
+	n.listings[msg.Dir] = dirListing{entries: msg.Entries, err: msg.Err}
+	if levels, ok := n.expandBelow[msg.Dir]; ok {
+		delete(n.expandBelow, msg.Dir)
+		for _, entry := range n.children(msg.Dir) {
+			if !entry.IsDir || entry.Link != "" {
+				continue // links could lead anywhere, even back up the tree
+			}
+			n.expanded[entry.Path] = true
+			if levels > 1 {
+				n.expandBelow[entry.Path] = levels - 1
+			}
+		}
+	}
+	if msg.Err != nil && msg.Dir == n.root {
+		n.status = "Cannot read " + msg.Dir + ": " + errorText(msg.Err)
+	}
+
+	current := n.SelectedPath()
+	cmd := n.loadEntries()
+	if n.want != "" && n.selectPath(n.want) {
+		n.want = ""
+	} else if !n.selectPath(current) && n.cursor >= len(n.entries) {
+		n.cursor = max(0, len(n.entries)-1)
+	}
+	n.adjustOffset()
+	return cmd
+}
+
+// selectPath moves the cursor to path if it is visible
+func (n *NavPane) selectPath(path string) bool {
+	for i, e := range n.entries {
+		if e.Path == path {
 			n.cursor = i
-			n.adjustOffset()
-			return
+			return true
 		}
 	}
+	return false
 }
 
-func (n *NavPane) loadEntries() {
+// flatten rebuilds the visible entries from the cached listings
+func (n *NavPane) flatten() {
+	if n.columns {
+		n.flattenColumn()
+		return
+	}
 	n.entries = nil
-	n.loadDir(n.root, 0)
+	n.flattenDir(n.root, 0)
 }
 
-func (n *NavPane) loadDir(dir string, depth int) {
-	files, err := os.ReadDir(dir)
-	if err != nil {
-		return
+func (n *NavPane) flattenDir(dir string, depth int) {
+	for _, entry := range n.children(dir) {
+		entry.Depth = depth
+		entry.Expanded = entry.IsDir && n.expanded[entry.Path]
+		_, listed := n.listings[entry.Path]
+		n.entries = append(n.entries, entry)
+
+		// If directory is expanded, show its contents
+		if entry.Expanded && listed {
+			n.flattenDir(entry.Path, depth+1)
+		}
 	}
+}
 
-	// Sort: directories first, then alphabetically
-	sort.Slice(files, func(i, j int) bool {
-		iDir := files[i].IsDir()
-		jDir := files[j].IsDir()
-		if iDir != jDir {
-			return iDir
+// readDirCmd reads a directory's children in the background, with their
+// badges if wanted
+func readDirCmd(dir string, badges bool, order sortOrder) tea.Cmd {
+	return func() tea.Msg {
+		entries, err := readDir(dir, order)
+		if err == nil && badges {
+			addBadges(dir, entries)
 		}
-		return strings.ToLower(files[i].Name()) < strings.ToLower(files[j].Name())
-	})
+		return DirLoadedMsg{Dir: dir, Entries: entries, Err: err}
+	}
+}
+
+// readDir lists a directory in the given order. Sizes and times are only
+// read when the order needs them.
+func readDir(dir string, order sortOrder) ([]FileEntry, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
 
+	entries := make([]FileEntry, 0, len(files))
+	keys := make(map[string]sortKey, len(files))
 	for _, f := range files {
-		name := f.Name()
-		// Skip hidden files (except .git for now)
-		if strings.HasPrefix(name, ".") && name != ".git" {
-			continue
+		path := filepath.Join(dir, f.Name())
+		entry := FileEntry{
+			Name:    f.Name(),
+			Path:    path,
+			IsDir:   f.IsDir(),
+			Special: specialKind(f.Type()),
 		}
+		if f.Type()&os.ModeSymlink != 0 {
+			// Links show where they point and act like their target
+			entry.Link, _ = os.Readlink(path)
+			info, err := os.Stat(path)
+			entry.Broken = err != nil
+			entry.IsDir = err == nil && info.IsDir()
+			if err == nil {
+				entry.Special = specialKind(info.Mode())
+			}
+		}
+		key := sortKey{name: entry.Name, dir: entry.IsDir}
+		if order.by != sortByName {
+			if info, err := f.Info(); err == nil {
+				key.size, key.modTime = info.Size(), info.ModTime()
+			}
+		}
+		keys[entry.Name] = key
+		entries = append(entries, entry)
+	}
 
-		path := filepath.Join(dir, name)
-		isExpanded := n.expanded[path]
-		entry := FileEntry{
-			Name:     name,
-			Path:     path,
-			IsDir:    f.IsDir(),
-			Expanded: isExpanded,
-			Depth:    depth,
+	sort.SliceStable(entries, func(i, j int) bool {
+		return order.less(keys[entries[i].Name], keys[entries[j].Name])
+	})
+	return entries, nil
+}
+
+// hiddenCount returns how many of dir's entries the hide patterns leave
+// out of the tree
+func (n *NavPane) hiddenCount(dir string) int {
+	if n.showHidden {
+		return 0
+	}
+	count := 0
+	for _, entry := range n.listings[dir].entries {
+		if isHidden(entry.Name, n.hide) && (entry.IsDir || !n.dirsOnly) {
+			count++
 		}
-		n.entries = append(n.entries, entry)
+	}
+	return count
+}
 
-		// If directory is expanded, load its contents
-		if entry.IsDir && isExpanded {
-			n.loadDir(path, depth+1)
+// isHidden reports whether name is hidden by the patterns: the last
+// pattern matching it decides, and "!" patterns unhide
+func isHidden(name string, patterns []string) bool {
+	hidden := false
+	for _, p := range patterns {
+		negate := strings.HasPrefix(p, "!")
+		if ok, _ := filepath.Match(strings.TrimPrefix(p, "!"), name); ok {
+			hidden = !negate
 		}
 	}
+	return hidden
 }
 
 func (n *NavPane) renderEntry(entry FileEntry, selected bool) string {
 	indent := strings.Repeat("  ", entry.Depth)
 
 	style := lipgloss.NewStyle()
-	if selected {
-		style = style.
-			Background(lipgloss.Color("62")).
-			Foreground(lipgloss.Color("230")).
-			Bold(true)
-	} else if entry.IsDir {
+	if entry.IsDir {
 		style = style.Foreground(lipgloss.Color("12"))
 	}
 
 	// Expando indicator for directories
 	expando := "  "
 	if entry.IsDir {
-		if entry.Expanded {
+		if entry.Loading {
+			expando = "⋯ "
+		} else if entry.Expanded {
 			expando = "▼ "
 		} else {
 			expando = "▶ "
 		}
 	}
 
-	name := entry.Name
+	marker := ""
+	if n.selected[entry.Path] {
+		marker = "● "
+		if !selected {
+			style = style.Foreground(lipgloss.Color("170"))
+		}
+	}
+	if entry.Broken && !selected {
+		style = style.Foreground(lipgloss.Color("203"))
+	}
+	if entry.Special != "" && !selected {
+		style = style.Foreground(lipgloss.Color("178"))
+	}
+
+	name, link := n.fitEntry(entry)
+	line := indent + expando + marker + name
 	if entry.IsDir {
-		name += "/"
+		line += "/"
 	}
+	line += specialGlyphs[entry.Special]
+	line += link + n.entryNotes(entry)
 
-	line := indent + expando + name
+	badge := entry.Badge
+	if entry.IsDir && n.badges {
+		badge = strings.TrimSpace(badge + " " + n.dirBadge(entry))
+	}
+	if selected {
+		return n.cursorStyle.render(n.withBadge(line, badge, true), n.width)
+	}
 
-	// Pad to width for selection highlight
-	if selected && n.width > 0 {
-		padding := n.width - lipgloss.Width(line)
-		if padding > 0 {
-			line += strings.Repeat(" ", padding)
+	return n.withBadge(style.Render(line), badge, false)
+}
+
+// specialGlyphs mark special files after their name, as ls -F does
+var specialGlyphs = map[string]string{
+	"fifo":             "|",
+	"socket":           "=",
+	"character device": "%",
+	"block device":     "%",
+}
+
+// entryNotes returns what is said after an entry's name: a broken link,
+// the file being edited, or an expanded directory with nothing shown
+func (n *NavPane) entryNotes(entry FileEntry) string {
+	notes := ""
+	if entry.Broken {
+		notes += " (broken)"
+	}
+	if entry.Path == n.editing {
+		notes += " ✎"
+	}
+	if listing, listed := n.listings[entry.Path]; entry.Expanded && listed && listing.err == nil && len(n.children(entry.Path)) == 0 {
+		// Nothing below: say whether it is empty or all hidden
+		if hidden := n.hiddenCount(entry.Path); hidden > 0 {
+			notes += " (" + plural(hidden, "hidden item") + ")"
+		} else {
+			notes += " (empty)"
 		}
 	}
+	return notes
+}
 
-	return style.Render(line)
+// fitEntry returns an entry's name and link, " -> target" or "", cut to
+// fit the pane beside its indent, markers, trailing slash and notes. The
+// link target is cut first, then the name, with an ellipsis where
+// configured; the scrollbar's column is left free.
+func (n *NavPane) fitEntry(entry FileEntry) (string, string) {
+	name, link := entry.Name, ""
+	if entry.Link != "" {
+		link = " -> " + entry.Link
+	}
+	fixed := 2*entry.Depth + 2 + ansi.StringWidth(n.entryNotes(entry))
+	if n.selected[entry.Path] {
+		fixed += 2
+	}
+	if entry.IsDir {
+		fixed++
+	}
+	fixed += ansi.StringWidth(specialGlyphs[entry.Special])
+	over := fixed + ansi.StringWidth(name) + ansi.StringWidth(link) - n.rowWidth()
+	if over <= 0 {
+		return name, link
+	}
+	if link != "" {
+		keep := max(ansi.StringWidth(" -> x…"), ansi.StringWidth(link)-over)
+		over -= ansi.StringWidth(link) - keep
+		link = ansi.Truncate(link, keep, "…")
+	}
+	if over > 0 {
+		name = n.truncate.shorten(name, max(1, ansi.StringWidth(name)-over))
+	}
+	return name, link
+}
+
+// rowWidth is the width of the tree's rows, less the scrollbar's column
+// when it is drawn
+func (n *NavPane) rowWidth() int {
+	if n.scrollbar.shown(n.treeHeight(), n.width, len(n.entries)) {
+		return n.width - 1
+	}
+	return n.width
+}
+
+// step moves the cursor one entry, wrapping around if enabled
+func (n *NavPane) step(delta int) {
+	n.cursor = stepCursor(n.cursor, delta, len(n.entries), n.wrap)
+	n.adjustOffset()
+}
+
+// stepSibling moves to the next entry in the same directory, skipping
+// over expanded children, or stays put when it is the last one
+func (n *NavPane) stepSibling(delta int) {
+	if n.cursor >= len(n.entries) {
+		return
+	}
+	depth := n.entries[n.cursor].Depth
+	for i := n.cursor + delta; i >= 0 && i < len(n.entries); i += delta {
+		if n.entries[i].Depth < depth {
+			return // left the directory
+		}
+		if n.entries[i].Depth == depth {
+			n.cursor = i
+			n.adjustOffset()
+			return
+		}
+	}
 }
 
 func (n *NavPane) moveCursor(delta int) {
@@ -287,73 +966,270 @@ func (n *NavPane) moveCursor(delta int) {
 	n.adjustOffset()
 }
 
+// treeHeight is the number of rows left for tree entries after the
+// header, favorites section and footer
+func (n *NavPane) treeHeight() int {
+	return n.height - 2 - n.favoritesHeight()
+}
+
 func (n *NavPane) adjustOffset() {
-	visibleHeight := n.height - 2
-	if visibleHeight < 1 {
-		visibleHeight = 1
+	n.offset = keepInView(n.cursor, n.offset, max(1, n.treeHeight()), len(n.entries), n.scrollOff)
+}
+
+func (n *NavPane) toggleOrOpen() tea.Cmd {
+	if n.cursor < 0 || n.cursor >= len(n.entries) {
+		return nil
 	}
 
-	// Scroll up if cursor above viewport
-	if n.cursor < n.offset {
-		n.offset = n.cursor
+	entry := n.entries[n.cursor]
+	if entry.IsDir && !entry.Broken && (n.dirsOnly || n.enterDescends) {
+		// Picker mode, and nav.enter "descend", re-root instead of
+		// expanding in place
+		n.root = entry.Path
+		n.cursor = 0
+		n.offset = 0
+		return n.loadEntries()
 	}
-	// Scroll down if cursor below viewport
-	if n.cursor >= n.offset+visibleHeight {
-		n.offset = n.cursor - visibleHeight + 1
+	if entry.IsDir {
+		return n.toggleExpanded()
 	}
+	return n.openEntry(n.focusOnOpen)
 }
 
-func (n *NavPane) toggleOrOpen() tea.Cmd {
+// openEntry opens the entry under the cursor in the viewer, moving focus
+// there if asked
+func (n *NavPane) openEntry(focus bool) tea.Cmd {
 	if n.cursor < 0 || n.cursor >= len(n.entries) {
 		return nil
 	}
+	entry := n.entries[n.cursor]
+	if entry.Broken {
+		n.status = "Broken link: " + entry.Name + " -> " + entry.Link + " (target does not exist)"
+		return nil
+	}
+	if entry.Link != "" {
+		return n.openLink(entry, focus)
+	}
+	return func() tea.Msg {
+		return FileSelectedMsg{Path: entry.Path, Focus: focus}
+	}
+}
 
+// toggleExpanded expands or collapses the directory under the cursor in
+// place
+func (n *NavPane) toggleExpanded() tea.Cmd {
+	if n.cursor < 0 || n.cursor >= len(n.entries) {
+		return nil
+	}
 	entry := n.entries[n.cursor]
-	if entry.IsDir {
-		n.expanded[entry.Path] = !n.expanded[entry.Path]
-		n.loadEntries()
-		// Try to keep cursor on same entry after reload
-		for i, e := range n.entries {
-			if e.Path == entry.Path {
-				n.cursor = i
-				break
-			}
-		}
+	if !entry.IsDir || entry.Broken {
 		return nil
 	}
-	// File selected - emit message to open in viewer
+	n.expanded[entry.Path] = !n.expanded[entry.Path]
+	cmd := n.loadEntries()
+	// A listing cached from an earlier expand may be stale: it is shown
+	// while the directory is read again
+	if _, listed := n.listings[entry.Path]; listed && n.expanded[entry.Path] && !n.loading[entry.Path] {
+		n.loading[entry.Path] = true
+		cmd = tea.Batch(cmd, readDirCmd(entry.Path, n.badges, n.order))
+	}
+	// Try to keep cursor on same entry after reload
+	n.selectPath(entry.Path)
+	return cmd
+}
+
+// openLink opens the file a symlink resolves to, noting whether it lies
+// outside the tree
+func (n *NavPane) openLink(entry FileEntry, focus bool) tea.Cmd {
+	target, err := filepath.EvalSymlinks(entry.Path)
+	if err != nil {
+		n.status = "Cannot resolve " + entry.Name + ": " + errorText(err)
+		return nil
+	}
+	root, err := filepath.EvalSymlinks(n.root)
+	if err != nil {
+		root = n.root
+	}
+	rel, err := filepath.Rel(root, target)
+	outside := err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator))
 	return func() tea.Msg {
-		return FileSelectedMsg{Path: entry.Path}
+		return FileSelectedMsg{Path: target, Via: entry.Path, Outside: outside, Focus: focus}
 	}
 }
 
-func (n *NavPane) collapseOrParent() {
+func (n *NavPane) collapseOrParent() tea.Cmd {
 	if n.cursor >= 0 && n.cursor < len(n.entries) {
 		entry := n.entries[n.cursor]
 		// If on expanded directory, collapse it
 		if entry.IsDir && entry.Expanded {
 			n.expanded[entry.Path] = false
-			n.loadEntries()
-			return
+			return n.loadEntries()
 		}
 	}
 	// Otherwise go to parent
-	n.goToParent()
+	return n.goToParent()
+}
+
+// collapseParent collapses an expanded directory, or else moves up to the
+// entry's parent in the tree and collapses that. Only a top-level entry
+// has to re-root to go up.
+func (n *NavPane) collapseParent() tea.Cmd {
+	if n.cursor < 0 || n.cursor >= len(n.entries) {
+		return n.goToParent()
+	}
+	entry := n.entries[n.cursor]
+	if entry.IsDir && entry.Expanded {
+		n.expanded[entry.Path] = false
+		return n.loadEntries()
+	}
+	if entry.Depth == 0 {
+		return n.goToParent()
+	}
+	parent := filepath.Dir(entry.Path)
+	n.expanded[parent] = false
+	cmd := n.loadEntries()
+	if n.selectPath(parent) {
+		n.adjustOffset()
+	}
+	return cmd
 }
 
-func (n *NavPane) goToParent() {
+func (n *NavPane) goToParent() tea.Cmd {
 	parent := filepath.Dir(n.root)
-	if parent != n.root {
-		oldRoot := n.root
-		n.root = parent
-		n.loadEntries()
-		// Try to select the directory we came from
-		for i, e := range n.entries {
-			if e.Path == oldRoot {
-				n.cursor = i
-				n.adjustOffset()
-				break
-			}
+	if parent == n.root {
+		return nil
+	}
+	oldRoot := n.root
+	n.root = parent
+	cmd := n.loadEntries()
+	// Select the directory we came from, now or once the parent is read
+	if n.selectPath(oldRoot) {
+		n.adjustOffset()
+	} else {
+		n.want = oldRoot
+	}
+	return cmd
+}
+
+// goToRepoRoot re-roots the tree at the top of the git repository
+// enclosing the selected entry, keeping the cursor on it. The repository
+// may lie above the root or, when the root is outside any, below it.
+func (n *NavPane) goToRepoRoot() tea.Cmd {
+	from := n.SelectedPath()
+	if from == "" {
+		from = n.root
+	}
+	top := findRepoRoot(from)
+	switch top {
+	case "":
+		n.status = "Not inside a git repository"
+		return nil
+	case n.root:
+		n.status = "Already at the repository root"
+		return nil
+	}
+	n.root = top
+	n.cursor = 0
+	n.offset = 0
+	n.status = "Repository root: " + top
+	return n.ExpandToPath(from)
+}
+
+// findRepoRoot walks up from dir to the nearest directory holding .git,
+// which is a file rather than a directory in worktrees and submodules.
+// It returns "" outside a repository.
+func findRepoRoot(dir string) string {
+	for {
+		if _, err := os.Lstat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+func (n *NavPane) toggleSelected() {
+	path := n.SelectedPath()
+	if path == "" {
+		return
+	}
+	if n.selected[path] {
+		delete(n.selected, path)
+	} else {
+		n.selected[path] = true
+	}
+	n.moveCursor(1)
+}
+
+// targets returns the selection in tree order, or the entry under the
+// cursor when nothing is selected
+func (n *NavPane) targets() []string {
+	var paths []string
+	for _, e := range n.entries {
+		if n.selected[e.Path] {
+			paths = append(paths, e.Path)
+		}
+	}
+	if len(paths) == 0 && n.SelectedPath() != "" {
+		paths = append(paths, n.SelectedPath())
+	}
+	return paths
+}
+
+// startOp begins an operation on the targets, asking for confirmation
+// or a destination before anything is touched
+func (n *NavPane) startOp(op FileOp) tea.Cmd {
+	paths := n.targets()
+	if len(paths) == 0 {
+		return nil
+	}
+	if op == OpDelete {
+		return askConfirm("delete", fmt.Sprintf("Delete %d item(s)?", len(paths)), func() tea.Cmd {
+			return runFileOp(OpDelete, paths, "")
+		}, nil)
+	}
+	label := fmt.Sprintf("%s %d to: ", op, len(paths))
+	return askInput(label, n.root+string(filepath.Separator), func(dest string) tea.Cmd {
+		if dest = n.typedPath(dest); dest == "" {
+			return nil
+		}
+		return runFileOp(op, paths, dest)
+	})
+}
+
+// startCreate prompts for the name of a new file, starting in the
+// directory at the cursor or holding it
+func (n *NavPane) startCreate() tea.Cmd {
+	dir := n.root
+	if path := n.SelectedPath(); path != "" {
+		dir = filepath.Dir(path)
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			dir = path
 		}
 	}
+	return askInput("new file: ", dir+string(filepath.Separator), func(path string) tea.Cmd {
+		if path = n.typedPath(path); path == "" {
+			return nil
+		}
+		return createFile(path, n.templateDir)
+	})
+}
+
+// SetTemplateDir sets where templates for new files are looked up, "" for
+// none
+func (n *NavPane) SetTemplateDir(dir string) {
+	n.templateDir = dir
+}
+
+// typedPath resolves a path typed at a prompt against the root, or returns
+// "" when nothing was typed
+func (n *NavPane) typedPath(typed string) string {
+	typed = strings.TrimSpace(typed)
+	if typed == "" || filepath.IsAbs(typed) {
+		return typed
+	}
+	return filepath.Join(n.root, typed)
 }
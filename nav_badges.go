@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// Limits that keep badges cheap: small files are read whole to count
+// lines, larger ones only sniffed, and past badgeFileLimit files in one
+// directory contents are not read at all
+const (
+	badgeReadLimit  = 64 << 10
+	badgeSniffBytes = 512
+	badgeFileLimit  = 200
+	badgeGitTimeout = 2 * time.Second
+)
+
+// changedBadge marks files and directories with uncommitted git changes
+const changedBadge = "★"
+
+var imageExts = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".webp": true,
+	".bmp": true, ".ico": true, ".tif": true, ".tiff": true, ".avif": true,
+}
+
+// SetBadges turns entry badges on or off and sets their color
+func (n *NavPane) SetBadges(on bool, color string) {
+	n.badges = on
+	n.badgeStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(color))
+}
+
+// toggleBadges turns badges on or off, re-reading the listings in view so
+// they gain or drop them
+func (n *NavPane) toggleBadges() tea.Cmd {
+	n.badges = !n.badges
+	n.status = "Badges off"
+	if n.badges {
+		n.status = "Badges on"
+	}
+	return n.refresh()
+}
+
+// withBadge right-aligns the entry's badge in the row's width. When the
+// name leaves too little room only the badge's first part is shown, or
+// none of it.
+func (n *NavPane) withBadge(line, badge string, selected bool) string {
+	if !n.badges || badge == "" {
+		return line
+	}
+	width := n.rowWidth()
+	gap := width - ansi.StringWidth(line) - ansi.StringWidth(badge)
+	if first, _, ok := strings.Cut(badge, " "); ok && gap < 1 {
+		badge = first
+		gap = width - ansi.StringWidth(line) - ansi.StringWidth(badge)
+	}
+	if gap < 1 {
+		return line
+	}
+	if selected {
+		return line + strings.Repeat(" ", gap) + badge
+	}
+	return line + strings.Repeat(" ", gap) + n.badgeStyle.Render(badge)
+}
+
+// addBadges notes something about each entry worth seeing without opening
+// it: git changes, the line count of small text files, and image or binary
+// content. Anything that fails just leaves the badge off.
+func addBadges(dir string, entries []FileEntry) {
+	changed := gitChanged(dir)
+	read := 0
+	for i := range entries {
+		e := &entries[i]
+		var parts []string
+		if changed[e.Name] {
+			parts = append(parts, changedBadge)
+		}
+		switch {
+		case e.IsDir:
+			// For telling whether the size walked for it is current
+			if info, err := os.Stat(e.Path); err == nil {
+				e.ModTime = info.ModTime()
+			}
+		case e.Broken || e.Special != "":
+		case imageExts[strings.ToLower(filepath.Ext(e.Name))]:
+			parts = append(parts, "img")
+		case read < badgeFileLimit:
+			read++
+			if b := contentBadge(e.Path); b != "" {
+				parts = append(parts, b)
+			}
+		}
+		e.Badge = strings.Join(parts, " ")
+	}
+}
+
+// contentBadge reads the start of a file: "bin" if it holds NUL bytes,
+// else the line count when the file is small enough to read whole
+func contentBadge(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		return ""
+	}
+
+	limit := int64(badgeSniffBytes)
+	if info.Size() <= badgeReadLimit {
+		limit = badgeReadLimit
+	}
+	data, err := io.ReadAll(io.LimitReader(f, limit))
+	if err != nil {
+		return ""
+	}
+	if bytes.IndexByte(data, 0) >= 0 {
+		return "bin"
+	}
+	if info.Size() > badgeReadLimit {
+		return ""
+	}
+	lines := bytes.Count(data, []byte("\n"))
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		lines++
+	}
+	return fmt.Sprintf("%d ln", lines)
+}
+
+// gitChanged returns the names in dir that have uncommitted changes or are
+// untracked, including directories holding such files. Outside a git work
+// tree, or if git is slow or missing, it returns nothing.
+func gitChanged(dir string) map[string]bool {
+	ctx, cancel := context.WithTimeout(context.Background(), badgeGitTimeout)
+	defer cancel()
+
+	// Porcelain paths are relative to the top of the work tree
+	prefix, err := exec.CommandContext(ctx, "git", "-C", dir, "rev-parse", "--show-prefix").Output()
+	if err != nil {
+		return nil
+	}
+	out, err := exec.CommandContext(ctx, "git", "-C", dir, "status", "--porcelain", "-z", "--", ".").Output()
+	if err != nil {
+		return nil
+	}
+
+	changed := make(map[string]bool)
+	fields := strings.Split(string(out), "\x00")
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+		if len(field) < 4 {
+			continue
+		}
+		if field[0] == 'R' || field[0] == 'C' {
+			i++ // the source path of a rename or copy follows
+		}
+		rel := strings.TrimPrefix(field[3:], strings.TrimSpace(string(prefix)))
+		name, _, _ := strings.Cut(rel, "/")
+		if name != "" {
+			changed[name] = true
+		}
+	}
+	return changed
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// minColumnWidth is the narrowest a column gets before earlier columns are
+// dropped from view
+const minColumnWidth = 14
+
+// Column mode shows one column per directory level from the root down to
+// the current directory, plus a preview of the selected subdirectory. The
+// current directory's children are the pane's entries, so selection, pins,
+// file operations and quick-jump work on the active column unchanged.
+
+// toggleColumns switches between the tree and columns, keeping the
+// selected path
+func (n *NavPane) toggleColumns() tea.Cmd {
+	current := n.SelectedPath()
+	n.columns = !n.columns
+	if n.columns {
+		n.colDir = n.root
+		if current != "" {
+			n.colDir = filepath.Dir(current)
+		}
+		n.cursor = 0
+		n.offset = 0
+		cmd := n.loadEntries()
+		if n.selectPath(current) {
+			n.adjustOffset()
+		} else {
+			n.want = current
+		}
+		return cmd
+	}
+	if current == "" {
+		return n.loadEntries()
+	}
+	return n.ExpandToPath(current)
+}
+
+// children returns a directory's cached entries that are shown: hidden
+// names and, in picker mode, files are left out
+func (n *NavPane) children(dir string) []FileEntry {
+	var entries []FileEntry
+	for _, entry := range n.listings[dir].entries {
+		if !n.showHidden && isHidden(entry.Name, n.hide) {
+			continue
+		}
+		if n.dirsOnly && !entry.IsDir {
+			continue
+		}
+		entry.Loading = n.loading[entry.Path]
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// flattenColumn makes the current directory's children the entries,
+// falling back to the root if the root moved away from it
+func (n *NavPane) flattenColumn() {
+	if rel, err := filepath.Rel(n.root, n.colDir); err != nil || strings.HasPrefix(rel, "..") {
+		n.colDir = n.root
+	}
+	n.entries = n.children(n.colDir)
+}
+
+// updateColumns handles the keys whose meaning changes in column mode,
+// reporting whether it did
+func (n *NavPane) updateColumns(msg tea.KeyMsg) (tea.Cmd, bool) {
+	switch msg.String() {
+	case "j", "down":
+		n.step(1)
+	case "k", "up":
+		n.step(-1)
+	case "g":
+		n.cursor = 0
+		n.offset = 0
+	case "G":
+		n.cursor = len(n.entries) - 1
+		n.adjustOffset()
+	case "enter", "l", "right":
+		if n.cursor >= len(n.entries) || !n.entries[n.cursor].IsDir || n.entries[n.cursor].Broken {
+			return nil, false // files open as in the tree
+		}
+		n.colDir = n.entries[n.cursor].Path
+		n.cursor = 0
+		n.offset = 0
+	case "h", "backspace", "left":
+		came := n.colDir
+		if n.colDir == n.root {
+			// Leaving the first column re-roots at the parent
+			if filepath.Dir(n.root) == n.root {
+				return nil, true
+			}
+			n.root = filepath.Dir(n.root)
+		}
+		n.colDir = filepath.Dir(came)
+		cmd := n.loadEntries()
+		if n.selectPath(came) {
+			n.adjustOffset()
+		} else {
+			n.want = came
+		}
+		return cmd, true
+	default:
+		return nil, false
+	}
+	// Read the current directory and the one being previewed
+	return n.loadEntries(), true
+}
+
+// previewDir returns the selected directory shown as the last column
+func (n *NavPane) previewDir() string {
+	if n.cursor < len(n.entries) && n.entries[n.cursor].IsDir && !n.entries[n.cursor].Broken {
+		return n.entries[n.cursor].Path
+	}
+	return ""
+}
+
+// columnDirs returns the directories shown as columns: the root down to
+// the current directory, then the preview
+func (n *NavPane) columnDirs() []string {
+	var dirs []string
+	for dir := n.colDir; ; dir = filepath.Dir(dir) {
+		dirs = append([]string{dir}, dirs...)
+		if dir == n.root || filepath.Dir(dir) == dir {
+			break
+		}
+	}
+	if preview := n.previewDir(); preview != "" {
+		dirs = append(dirs, preview)
+	}
+	return dirs
+}
+
+func (n *NavPane) viewColumns() string {
+	header := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("12")).
+		Render(displayPath(n.colDir, n.root))
+	lines := append([]string{header}, n.renderFavorites()...)
+	lines = append(lines, n.renderColumns(max(1, n.treeHeight()))...)
+	lines = append(lines, n.footer())
+	return strings.Join(lines, "\n")
+}
+
+// displayPath shows dir relative to the parent of root, so the root's
+// name leads
+func displayPath(dir, root string) string {
+	rel, err := filepath.Rel(filepath.Dir(root), dir)
+	if err != nil {
+		return dir
+	}
+	return rel
+}
+
+// renderColumns lays out as many trailing columns as fit the pane, each
+// height rows tall
+func (n *NavPane) renderColumns(height int) []string {
+	dirs := n.columnDirs()
+	count := min(len(dirs), max(1, n.width/minColumnWidth))
+	dirs = dirs[len(dirs)-count:]
+	width := max(1, (n.width-(count-1))/count)
+
+	cols := make([][]string, len(dirs))
+	for i, dir := range dirs {
+		switch {
+		case dir == n.colDir:
+			cols[i] = n.renderActiveColumn(width, height)
+		case i == len(dirs)-1 && dir == n.previewDir():
+			cols[i] = n.renderOtherColumn(dir, "", width, height)
+		default:
+			// An ancestor: mark the directory on the way to the current one
+			cols[i] = n.renderOtherColumn(dir, dirs[i+1], width, height)
+		}
+	}
+
+	sep := lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Render("│")
+	lines := make([]string, height)
+	for row := range lines {
+		var parts []string
+		for _, col := range cols {
+			parts = append(parts, col[row])
+		}
+		lines[row] = strings.Join(parts, sep)
+	}
+	return lines
+}
+
+func (n *NavPane) renderActiveColumn(width, height int) []string {
+	rows := make([]string, 0, height)
+	if len(n.entries) == 0 {
+		rows = append(rows, padCell(n.emptyText(n.colDir), width, lipgloss.NewStyle()))
+	}
+	for i := n.offset; i < len(n.entries) && len(rows) < height; i++ {
+		entry := n.entries[i]
+		style := lipgloss.NewStyle()
+		switch {
+		case n.selected[entry.Path]:
+			style = style.Foreground(lipgloss.Color("170"))
+		case entry.IsDir:
+			style = style.Foreground(lipgloss.Color("12"))
+		}
+		line := padCell(columnName(entry, n.selected[entry.Path]), width, style)
+		if i == n.cursor {
+			// Keep the column width when only the text is highlighted
+			line = n.cursorStyle.render(ansi.Truncate(columnName(entry, n.selected[entry.Path]), width, "…"), width)
+			line += strings.Repeat(" ", max(0, width-ansi.StringWidth(line)))
+		}
+		if n.jumping {
+			line = n.jumpLabel(line, i)
+		}
+		rows = append(rows, line)
+	}
+	for len(rows) < height {
+		rows = append(rows, strings.Repeat(" ", width))
+	}
+	return rows
+}
+
+// renderOtherColumn renders a directory that is not the current one,
+// highlighting mark and scrolling so it is visible
+func (n *NavPane) renderOtherColumn(dir, mark string, width, height int) []string {
+	entries := n.children(dir)
+	rows := make([]string, 0, height)
+	if len(entries) == 0 {
+		rows = append(rows, padCell(n.emptyText(dir), width, lipgloss.NewStyle().Foreground(lipgloss.Color("245"))))
+	}
+	offset := 0
+	for i, e := range entries {
+		if e.Path == mark {
+			offset = max(0, min(i-height/2, len(entries)-height))
+		}
+	}
+	for i := offset; i < len(entries) && len(rows) < height; i++ {
+		entry := entries[i]
+		style := lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
+		if entry.Path == mark {
+			style = style.Background(lipgloss.Color("237")).Foreground(lipgloss.Color("12"))
+		}
+		rows = append(rows, padCell(columnName(entry, false), width, style))
+	}
+	for len(rows) < height {
+		rows = append(rows, strings.Repeat(" ", width))
+	}
+	return rows
+}
+
+// emptyText describes a directory with nothing to list
+func (n *NavPane) emptyText(dir string) string {
+	if n.loading[dir] {
+		return "Loading…"
+	}
+	if err := n.listings[dir].err; err != nil {
+		return errorText(err)
+	}
+	if hidden := n.hiddenCount(dir); hidden > 0 {
+		return "(" + plural(hidden, "hidden item") + ")"
+	}
+	return "(empty)"
+}
+
+func columnName(entry FileEntry, marked bool) string {
+	name := entry.Name
+	if entry.IsDir {
+		name += "/"
+	}
+	if marked {
+		name = "● " + name
+	}
+	if entry.Broken {
+		name += " (broken)"
+	}
+	return name
+}
+
+// padCell fits text to exactly width columns and styles it
+func padCell(text string, width int, style lipgloss.Style) string {
+	text = ansi.Truncate(text, width, "…")
+	return style.Render(text + strings.Repeat(" ", max(0, width-ansi.StringWidth(text))))
+}
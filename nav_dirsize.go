@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// DirSizeMsg carries the result of walking a directory for its size
+type DirSizeMsg struct {
+	Path    string
+	ModTime time.Time // of the directory when the walk started
+	Size    int64
+	Files   int
+	Skipped int // subdirectories that could not be read
+	Walk    int // the badge walk it answers, 0 for one started with S
+	Err     error
+}
+
+// badgeWalkLimit is how many directories are sized for badges at once
+const badgeWalkLimit = 4
+
+// badgeWalk is a background walk sizing a directory for its badge
+type badgeWalk struct {
+	id      int
+	modTime time.Time
+	cancel  context.CancelFunc
+}
+
+// dirSize is a finished walk, valid while the directory's mtime is the
+// same. A walk that failed is kept too, so it is not retried until the
+// directory changes.
+type dirSize struct {
+	modTime time.Time
+	size    int64
+	files   int
+	skipped int
+	err     error
+}
+
+// startDirSize sizes the directory under the cursor, from the cache when
+// it has not changed since, otherwise by walking it in the background
+func (n *NavPane) startDirSize() tea.Cmd {
+	if n.cursor < 0 || n.cursor >= len(n.entries) || !n.entries[n.cursor].IsDir {
+		n.status = "Not a directory"
+		return nil
+	}
+	path := n.entries[n.cursor].Path
+	info, err := os.Stat(path)
+	if err != nil {
+		n.status = "Cannot read " + filepath.Base(path) + ": " + errorText(err)
+		return nil
+	}
+	if cached, ok := n.dirSizes[path]; ok && cached.err == nil && cached.modTime.Equal(info.ModTime()) {
+		n.status = n.dirSizeText(path, cached)
+		return nil
+	}
+
+	starting := n.sizing == ""
+	n.sizing = path
+	walk := walkDirSize(context.Background(), path, info.ModTime(), 0)
+	if !starting {
+		return walk // the spinner is already turning
+	}
+	n.spinner = spinner.New(spinner.WithSpinner(spinner.MiniDot))
+	return tea.Batch(n.spinner.Tick, walk)
+}
+
+// walkDirSize adds up the sizes of the regular files under dir, without
+// following symlinks, until ctx is cancelled
+func walkDirSize(ctx context.Context, dir string, modTime time.Time, walk int) tea.Cmd {
+	return func() tea.Msg {
+		msg := DirSizeMsg{Path: dir, ModTime: modTime, Walk: walk}
+		msg.Err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err != nil {
+				if path == dir {
+					return err
+				}
+				msg.Skipped++
+				return nil // an unreadable subdirectory only leaves a gap
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			if info, err := d.Info(); err == nil {
+				msg.Size += info.Size()
+				msg.Files++
+			}
+			return nil
+		})
+		return msg
+	}
+}
+
+// dirSizeDone caches a finished walk, or one that failed other than by
+// being cancelled, and reports it if it is the one still awaited
+func (n *NavPane) dirSizeDone(msg DirSizeMsg) {
+	if !errors.Is(msg.Err, context.Canceled) {
+		n.dirSizes[msg.Path] = dirSize{modTime: msg.ModTime, size: msg.Size, files: msg.Files, skipped: msg.Skipped, err: msg.Err}
+	}
+	if w, ok := n.badgeWalks[msg.Path]; ok && msg.Walk == w.id {
+		delete(n.badgeWalks, msg.Path)
+	}
+	if msg.Walk != 0 || msg.Path != n.sizing {
+		return
+	}
+	n.sizing = ""
+	if msg.Err != nil {
+		n.status = "Cannot size " + filepath.Base(msg.Path) + ": " + errorText(msg.Err)
+		return
+	}
+	n.status = n.dirSizeText(msg.Path, n.dirSizes[msg.Path])
+}
+
+// tickSpinner advances the sizing spinner, letting it stop once the walk
+// is done
+func (n *NavPane) tickSpinner(msg spinner.TickMsg) tea.Cmd {
+	if n.sizing == "" {
+		return nil
+	}
+	var cmd tea.Cmd
+	n.spinner, cmd = n.spinner.Update(msg)
+	return cmd
+}
+
+func (n *NavPane) dirSizeText(path string, s dirSize) string {
+	text := filepath.Base(path) + ": " + formatSize(s.size, n.exactSizes) + " in " + plural(s.files, "file")
+	if s.skipped > 0 {
+		text += fmt.Sprintf(" (%d unreadable skipped)", s.skipped)
+	}
+	return text
+}
+
+// sizeBadgeDirs keeps the directories in view sized for their badges: it
+// starts walks, a few at a time, for those without a size for their
+// current mtime, and cancels walks for those scrolled out of view,
+// collapsed away or changed since
+func (n *NavPane) sizeBadgeDirs() tea.Cmd {
+	var want []FileEntry
+	if n.badges && !n.columns {
+		end := min(n.offset+max(1, n.treeHeight()), len(n.entries))
+		for _, e := range n.entries[min(n.offset, end):end] {
+			if e.IsDir && !e.ModTime.IsZero() && !n.hasDirSize(e) {
+				want = append(want, e)
+			}
+		}
+	}
+	for path, w := range n.badgeWalks {
+		keep := slices.ContainsFunc(want, func(e FileEntry) bool {
+			return e.Path == path && e.ModTime.Equal(w.modTime)
+		})
+		if !keep {
+			w.cancel()
+			delete(n.badgeWalks, path)
+		}
+	}
+
+	var cmds []tea.Cmd
+	for _, e := range want {
+		if len(n.badgeWalks) >= badgeWalkLimit {
+			break
+		}
+		if _, running := n.badgeWalks[e.Path]; running {
+			continue
+		}
+		n.badgeWalkSeq++
+		ctx, cancel := context.WithCancel(context.Background())
+		n.badgeWalks[e.Path] = badgeWalk{id: n.badgeWalkSeq, modTime: e.ModTime, cancel: cancel}
+		cmds = append(cmds, walkDirSize(ctx, e.Path, e.ModTime, n.badgeWalkSeq))
+	}
+	return tea.Batch(cmds...)
+}
+
+// hasDirSize reports whether a directory's size, or the failure to find
+// it, is known for its mtime
+func (n *NavPane) hasDirSize(e FileEntry) bool {
+	s, ok := n.dirSizes[e.Path]
+	return ok && s.modTime.Equal(e.ModTime)
+}
+
+// dirBadge is a directory's size and file count once a walk has found
+// them, "…" while one is running and "unreadable" if one failed
+func (n *NavPane) dirBadge(e FileEntry) string {
+	if n.hasDirSize(e) {
+		s := n.dirSizes[e.Path]
+		if s.err != nil {
+			return "unreadable"
+		}
+		return formatSize(s.size, n.exactSizes) + " " + plural(s.files, "file")
+	}
+	if _, running := n.badgeWalks[e.Path]; running {
+		return "…"
+	}
+	return ""
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// SetFavorites sets the pinned directories and the file they are saved to
+// when changed
+func (n *NavPane) SetFavorites(dirs []string, path string) {
+	n.favorites = dirs
+	n.favoritesPath = path
+}
+
+// favoritesHeight is the number of rows the favorites section takes: one
+// per pin, capped to a third of the pane, plus a separator
+func (n *NavPane) favoritesHeight() int {
+	if len(n.favorites) == 0 {
+		return 0
+	}
+	return min(len(n.favorites), max(1, n.height/3)) + 1
+}
+
+func (n *NavPane) renderFavorites() []string {
+	if len(n.favorites) == 0 {
+		return nil
+	}
+	style := lipgloss.NewStyle().Foreground(lipgloss.Color("214"))
+	current := style.Bold(true).Underline(true)
+	home, _ := os.UserHomeDir()
+
+	var lines []string
+	for _, dir := range n.favorites[:n.favoritesHeight()-1] {
+		name := filepath.Base(dir)
+		if dir == home {
+			name = "~"
+		}
+		if dir == n.root {
+			lines = append(lines, current.Render("★ "+name))
+		} else {
+			lines = append(lines, style.Render("★ "+name))
+		}
+	}
+	sep := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	lines = append(lines, sep.Render(strings.Repeat("─", max(1, n.width))))
+	return lines
+}
+
+// togglePin pins the selected directory, or unpins it if already pinned,
+// and saves the list
+func (n *NavPane) togglePin() {
+	if n.cursor < 0 || n.cursor >= len(n.entries) || !n.entries[n.cursor].IsDir {
+		n.status = "Only directories can be pinned"
+		return
+	}
+	dir := n.entries[n.cursor].Path
+	if i := slices.Index(n.favorites, dir); i >= 0 {
+		n.favorites = slices.Delete(n.favorites, i, i+1)
+		n.status = "Unpinned " + filepath.Base(dir)
+	} else {
+		n.favorites = append(n.favorites, dir)
+		n.status = "Pinned " + filepath.Base(dir)
+	}
+	n.adjustOffset()
+
+	if n.favoritesPath != "" {
+		if err := saveJSON(n.favoritesPath, n.favorites); err != nil {
+			n.status = "Could not save favorites: " + err.Error()
+		}
+	}
+}
+
+// jumpFavorite re-roots the tree at the next (or previous) favorite after
+// the current root
+func (n *NavPane) jumpFavorite(delta int) tea.Cmd {
+	if len(n.favorites) == 0 {
+		n.status = "No favorites; press p on a directory to pin it"
+		return nil
+	}
+	i := slices.Index(n.favorites, n.root)
+	if i < 0 && delta < 0 {
+		i = 0
+	}
+	i = (i + delta + len(n.favorites)) % len(n.favorites)
+
+	dir := n.favorites[i]
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		n.status = "Favorite is not available: " + dir
+		return nil
+	}
+	n.root = dir
+	n.cursor = 0
+	n.offset = 0
+	return n.loadEntries()
+}
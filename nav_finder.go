@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// finderBatchSize and finderBatchInterval bound how long walk results wait
+// before being sent to the UI, so matches stream in while the walk runs
+const (
+	finderBatchSize     = 500
+	finderBatchInterval = 100 * time.Millisecond
+)
+
+// finder searches the files under the nav root by name. The tree is
+// walked in the background and matches appear as they are found.
+type finder struct {
+	id      int // walk the results belong to; stale batches are dropped
+	root    string
+	input   textinput.Model
+	paths   []string // paths found so far, relative to root
+	matches []string
+	cursor  int
+	offset  int
+	scanned int
+	done    bool
+	err     error
+	cancel  context.CancelFunc
+}
+
+// FinderBatchMsg carries files found by a finder walk since the last batch
+type FinderBatchMsg struct {
+	ID      int
+	Paths   []string
+	Scanned int // entries visited so far
+	Done    bool
+	Err     error
+	next    <-chan FinderBatchMsg
+}
+
+// openFinder starts walking the tree and shows the finder prompt
+func (n *NavPane) openFinder() tea.Cmd {
+	n.finderWalks++
+	ctx, cancel := context.WithCancel(context.Background())
+	ti := textinput.New()
+	ti.Prompt = "/"
+	n.finder = &finder{id: n.finderWalks, root: n.root, input: ti, cancel: cancel}
+	var hide []string
+	if !n.showHidden {
+		hide = n.hide
+	}
+	ch := walkFiles(ctx, n.root, hide)
+	return tea.Batch(n.finder.input.Focus(), waitFinder(n.finder.id, ch))
+}
+
+// closeFinder stops any walk still running and returns to the tree
+func (n *NavPane) closeFinder() {
+	n.finder.cancel()
+	n.finder = nil
+}
+
+// walkFiles walks root in a goroutine, sending batches of file paths until
+// the walk ends or ctx is cancelled. Names hidden by the patterns are
+// skipped, along with everything under hidden directories.
+func walkFiles(ctx context.Context, root string, hide []string) <-chan FinderBatchMsg {
+	ch := make(chan FinderBatchMsg, 1)
+	go func() {
+		defer close(ch)
+		var batch []string
+		scanned := 0
+		last := time.Now()
+		send := func(done bool, err error) bool {
+			select {
+			case ch <- FinderBatchMsg{Paths: batch, Scanned: scanned, Done: done, Err: err}:
+				batch = nil
+				last = time.Now()
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err != nil {
+				return nil // unreadable entries are skipped, not fatal
+			}
+			scanned++
+			// Repository internals are never worth finding, even when the
+			// tree shows .git
+			if path != root && (isHidden(d.Name(), hide) || (d.IsDir() && d.Name() == ".git")) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !d.IsDir() {
+				rel, _ := filepath.Rel(root, path)
+				batch = append(batch, rel)
+			}
+			if len(batch) >= finderBatchSize || time.Since(last) >= finderBatchInterval {
+				if !send(false, nil) {
+					return ctx.Err()
+				}
+			}
+			return nil
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		send(true, err)
+	}()
+	return ch
+}
+
+// waitFinder waits for the next batch from a walk
+func waitFinder(id int, ch <-chan FinderBatchMsg) tea.Cmd {
+	return func() tea.Msg {
+		batch, ok := <-ch
+		if !ok {
+			return nil
+		}
+		batch.ID = id
+		batch.next = ch
+		return batch
+	}
+}
+
+// finderBatch adds streamed results and waits for more
+func (n *NavPane) finderBatch(msg FinderBatchMsg) tea.Cmd {
+	f := n.finder
+	if f == nil || msg.ID != f.id {
+		return nil
+	}
+	f.paths = append(f.paths, msg.Paths...)
+	f.scanned = msg.Scanned
+	f.err = msg.Err
+	query, fold := f.input.Value(), n.caseMode.ignoreCase(f.input.Value())
+	for _, p := range msg.Paths {
+		if fuzzyMatch(p, query, fold) {
+			f.matches = append(f.matches, p)
+		}
+	}
+	if msg.Done {
+		f.done = true
+		return nil
+	}
+	return waitFinder(f.id, msg.next)
+}
+
+// refilter recomputes the matches after the query or case mode changed
+func (f *finder) refilter(mode caseMode) {
+	query := f.input.Value()
+	fold := mode.ignoreCase(query)
+	f.matches = f.matches[:0]
+	for _, p := range f.paths {
+		if fuzzyMatch(p, query, fold) {
+			f.matches = append(f.matches, p)
+		}
+	}
+	f.cursor = 0
+	f.offset = 0
+}
+
+// fuzzyMatch reports whether the query's characters appear in order in
+// path, ignoring case if fold is set
+func fuzzyMatch(path, query string, fold bool) bool {
+	if fold {
+		path, query = strings.ToLower(path), strings.ToLower(query)
+	}
+	for _, r := range query {
+		i := strings.IndexRune(path, r)
+		if i < 0 {
+			return false
+		}
+		path = path[i+len(string(r)):]
+	}
+	return true
+}
+
+func (n *NavPane) updateFinder(msg tea.KeyMsg) tea.Cmd {
+	f := n.finder
+	switch msg.String() {
+	case "esc":
+		n.closeFinder()
+		return nil
+	case "down", "ctrl+n", "ctrl+j":
+		f.cursor = min(f.cursor+1, len(f.matches)-1)
+	case "up", "ctrl+p", "ctrl+k":
+		f.cursor = max(f.cursor-1, 0)
+	case "ctrl+t":
+		n.caseMode = n.caseMode.next()
+		f.refilter(n.caseMode)
+	case "enter":
+		if f.cursor >= len(f.matches) {
+			return nil
+		}
+		path := filepath.Join(f.root, f.matches[f.cursor])
+		n.closeFinder()
+		reveal, focus := n.ExpandToPath(path), n.focusOnOpen
+		return tea.Batch(reveal, func() tea.Msg {
+			return FileSelectedMsg{Path: path, Focus: focus}
+		})
+	default:
+		before := f.input.Value()
+		var cmd tea.Cmd
+		f.input, cmd = f.input.Update(msg)
+		if f.input.Value() != before {
+			f.refilter(n.caseMode)
+		}
+		return cmd
+	}
+
+	listHeight := n.finderListHeight()
+	if f.cursor < f.offset {
+		f.offset = f.cursor
+	}
+	if f.cursor >= f.offset+listHeight {
+		f.offset = f.cursor - listHeight + 1
+	}
+	return nil
+}
+
+// finderListHeight is the number of result rows below the header and
+// prompt, keeping the last line for progress
+func (n *NavPane) finderListHeight() int {
+	return max(1, n.height-3)
+}
+
+func (n *NavPane) viewFinder() string {
+	f := n.finder
+	header := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("12")).
+		Render("Find in " + filepath.Base(f.root))
+	lines := []string{header, f.input.View()}
+
+	end := min(f.offset+n.finderListHeight(), len(f.matches))
+	for i := f.offset; i < end; i++ {
+		// Keep the file name visible by cutting long paths from the left
+		line := f.matches[i]
+		if w := ansi.StringWidth(line); w > n.width {
+			line = "…" + ansi.TruncateLeft(line, w-n.width+1, "")
+		}
+		if i == f.cursor {
+			line = n.cursorStyle.render(line, n.width)
+		}
+		lines = append(lines, line)
+	}
+
+	for len(lines) < n.height-1 {
+		lines = append(lines, "")
+	}
+
+	style := lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
+	progress := fmt.Sprintf("%d/%d files, scanning… (%d entries)", len(f.matches), len(f.paths), f.scanned)
+	if f.done {
+		progress = fmt.Sprintf("%d/%d files", len(f.matches), len(f.paths))
+	}
+	progress += ", case: " + n.caseMode.String() + " (ctrl+t)"
+	if f.err != nil {
+		progress += " (" + errorText(f.err) + ")"
+	}
+	lines = append(lines, style.Render(ansi.Truncate(progress, n.width, "…")))
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// jumpAlphabet supplies quick-jump labels, home row first
+const jumpAlphabet = "asdfghjklqwertyuiopzxcvbnm"
+
+// jumpLabels returns count labels. They are all one character when the
+// alphabet suffices and all two otherwise, so no label is a prefix of
+// another.
+func jumpLabels(count int) []string {
+	labels := make([]string, 0, count)
+	if count <= len(jumpAlphabet) {
+		for i := 0; i < count; i++ {
+			labels = append(labels, jumpAlphabet[i:i+1])
+		}
+		return labels
+	}
+	for _, a := range jumpAlphabet {
+		for _, b := range jumpAlphabet {
+			if len(labels) == count {
+				return labels
+			}
+			labels = append(labels, string(a)+string(b))
+		}
+	}
+	return labels
+}
+
+// startJump labels the visible entries and waits for one to be typed
+func (n *NavPane) startJump() {
+	if len(n.entries) == 0 {
+		return
+	}
+	n.jumping = true
+	n.jumpTyped = ""
+}
+
+// visibleRange returns the indexes of the first and one past the last
+// entry on screen
+func (n *NavPane) visibleRange() (int, int) {
+	return n.offset, min(n.offset+max(0, n.treeHeight()), len(n.entries))
+}
+
+func (n *NavPane) updateJump(msg tea.KeyMsg) tea.Cmd {
+	if msg.String() == "esc" || msg.Type != tea.KeyRunes {
+		n.jumping = false
+		return nil
+	}
+	n.jumpTyped += msg.String()
+
+	first, end := n.visibleRange()
+	matched := false
+	for i, label := range jumpLabels(end - first) {
+		if label == n.jumpTyped {
+			n.jumping = false
+			n.cursor = first + i
+			n.adjustOffset()
+			return nil
+		}
+		if strings.HasPrefix(label, n.jumpTyped) {
+			matched = true
+		}
+	}
+	if !matched {
+		n.jumping = false
+		n.status = "No entry labelled " + n.jumpTyped
+	}
+	return nil
+}
+
+// jumpLabel overlays an entry's label, if it has one, on the start of its
+// rendered line
+func (n *NavPane) jumpLabel(line string, index int) string {
+	first, end := n.visibleRange()
+	labels := jumpLabels(end - first)
+	if index < first || index >= end {
+		return line
+	}
+	label := labels[index-first]
+	if !strings.HasPrefix(label, n.jumpTyped) {
+		return line
+	}
+	style := lipgloss.NewStyle().
+		Background(lipgloss.Color("214")).
+		Foreground(lipgloss.Color("0")).
+		Bold(true)
+	return style.Render(label) + ansi.Cut(line, len(label), max(len(label), ansi.StringWidth(line)))
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestNavReexpandRereads collapses a directory, adds a file to it and
+// expands it again, which must list the new file rather than the cached
+// listing
+func TestNavReexpandRereads(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "sub")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	n := NewNavPane(root)
+	n.SetSize(40, 20)
+	settle(n, n.Init())
+	if !n.selectPath(sub) {
+		t.Fatal("sub not listed")
+	}
+	settle(n, n.toggleExpanded())
+	settle(n, n.toggleExpanded())
+
+	added := filepath.Join(sub, "new.txt")
+	if err := os.WriteFile(added, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	settle(n, n.toggleExpanded())
+	if !n.selectPath(added) {
+		t.Errorf("new.txt not listed after expanding sub again")
+	}
+}
+
+// TestNavDirSizeFailureCached fails a badge walk, which must show as a
+// badge and not be walked again while the directory is unchanged
+func TestNavDirSizeFailureCached(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "sub")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	n := NewNavPane(root)
+	n.SetSize(40, 20)
+	n.SetBadges(true, "245")
+	settle(n, n.Init())
+	if !n.selectPath(sub) {
+		t.Fatal("sub not listed")
+	}
+	entry := n.entries[n.cursor]
+
+	n.dirSizeDone(DirSizeMsg{Path: sub, ModTime: entry.ModTime, Walk: 1, Err: fs.ErrPermission})
+	if got := n.dirBadge(entry); got != "unreadable" {
+		t.Errorf("badge %q after a failed walk", got)
+	}
+	if n.sizeBadgeDirs() != nil || len(n.badgeWalks) > 0 {
+		t.Errorf("sub walked again though it has not changed")
+	}
+}
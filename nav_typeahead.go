@@ -0,0 +1,87 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// typeAheadTimeout is how long type-ahead waits for the next character
+// before it ends
+const typeAheadTimeout = time.Second
+
+// TypeAheadIdleMsg ends type-ahead when nothing was typed for a while
+type TypeAheadIdleMsg struct {
+	Seq int // keystroke the timer was started for; later ones restart it
+}
+
+// startTypeAhead waits for the start of a name to be typed
+func (n *NavPane) startTypeAhead() tea.Cmd {
+	n.typing = true
+	n.typed = ""
+	return n.typeAheadTimer()
+}
+
+// typeAheadTimer ends type-ahead once the current keystroke is the last
+func (n *NavPane) typeAheadTimer() tea.Cmd {
+	n.typedSeq++
+	seq := n.typedSeq
+	return tea.Tick(typeAheadTimeout, func(time.Time) tea.Msg {
+		return TypeAheadIdleMsg{Seq: seq}
+	})
+}
+
+func (n *NavPane) typeAheadIdle(msg TypeAheadIdleMsg) {
+	if msg.Seq == n.typedSeq {
+		n.typing = false
+	}
+}
+
+// updateTypeAhead adds a typed character to the prefix and selects the
+// next entry starting with it. Other keys end type-ahead, and all but esc
+// and enter are handled as usual.
+func (n *NavPane) updateTypeAhead(msg tea.KeyMsg) (tea.Cmd, bool) {
+	switch msg.Type {
+	case tea.KeyRunes, tea.KeySpace:
+		n.typed += string(msg.Runes)
+	case tea.KeyBackspace:
+		if n.typed == "" {
+			n.typing = false
+			return nil, true
+		}
+		typed := []rune(n.typed)
+		n.typed = string(typed[:len(typed)-1])
+	case tea.KeyEsc, tea.KeyEnter:
+		n.typing = false
+		return nil, true
+	default:
+		n.typing = false
+		return nil, false
+	}
+	n.selectPrefix(n.typed)
+	return n.typeAheadTimer(), true
+}
+
+// selectPrefix moves to the first entry from the cursor on, wrapping
+// around, whose name starts with prefix, noting when there is none
+func (n *NavPane) selectPrefix(prefix string) {
+	n.typedMiss = false
+	fold := n.caseMode.ignoreCase(prefix)
+	if fold {
+		prefix = strings.ToLower(prefix)
+	}
+	for i := range n.entries {
+		j := (n.cursor + i) % len(n.entries)
+		name := n.entries[j].Name
+		if fold {
+			name = strings.ToLower(name)
+		}
+		if strings.HasPrefix(name, prefix) {
+			n.cursor = j
+			n.adjustOffset()
+			return
+		}
+	}
+	n.typedMiss = true
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"os/exec"
+	"runtime"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ExternalOpenedMsg is sent after a file or URL was handed to the OS
+type ExternalOpenedMsg struct {
+	Target string
+	Err    error
+}
+
+// Status describes the outcome for a status line
+func (m ExternalOpenedMsg) Status() string {
+	if errors.Is(m.Err, exec.ErrNotFound) {
+		name, _ := openerCommand(m.Target)
+		return "Could not open " + m.Target + ": " + name + " not found"
+	}
+	if m.Err != nil {
+		return "Could not open " + m.Target + ": " + m.Err.Error()
+	}
+	return "Opened " + m.Target + " with the system default"
+}
+
+// openExternal launches the platform's default handler for target without
+// waiting for it to exit
+func openExternal(target string) tea.Cmd {
+	return func() tea.Msg {
+		name, args := openerCommand(target)
+		cmd := exec.Command(name, args...)
+		err := cmd.Start()
+		if err == nil {
+			go cmd.Wait() // reap the opener whenever it exits
+		}
+		return ExternalOpenedMsg{Target: target, Err: err}
+	}
+}
+
+// openerCommand returns the OS default-handler command for target
+func openerCommand(target string) (string, []string) {
+	switch runtime.GOOS {
+	case "darwin":
+		return "open", []string{target}
+	case "windows":
+		return "rundll32", []string{"url.dll,FileProtocolHandler", target}
+	}
+	return "xdg-open", []string{target}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// defaultBinaryPreview is how much of a binary file the text viewer shows
+const defaultBinaryPreview = 4 << 10
+
+// previewBinary reads the start of a binary file for the text viewer, as
+// lines with everything but printable text escaped, so neither the size
+// of the file nor its control bytes reach the terminal
+func previewBinary(path string, limit int) FileLoadedMsg {
+	f, err := os.Open(path)
+	if err != nil {
+		return FileLoadedMsg{Path: path, Err: err}
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return FileLoadedMsg{Path: path, Err: err}
+	}
+	data := make([]byte, limit)
+	n, err := io.ReadFull(f, data)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return FileLoadedMsg{Path: path, Err: err}
+	}
+	lines := strings.Split(escapeBinary(data[:n]), "\n")
+	index := heldIndex(lines, info.Size(), fileFormat{encoding: "binary"})
+	return FileLoadedMsg{Path: path, Index: index, Shown: int64(n)}
+}
+
+// escapeBinary keeps printable text, tabs and newlines and writes every
+// other byte as \xNN
+func escapeBinary(data []byte) string {
+	var b strings.Builder
+	for len(data) > 0 {
+		r, size := utf8.DecodeRune(data)
+		if r == '\t' || r == '\n' || r != utf8.RuneError && unicode.IsPrint(r) {
+			b.Write(data[:size])
+		} else {
+			for _, c := range data[:size] {
+				fmt.Fprintf(&b, `\x%02x`, c)
+			}
+		}
+		data = data[size:]
+	}
+	return b.String()
+}
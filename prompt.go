@@ -0,0 +1,82 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// PromptMsg asks for a line of text on the bottom line. Until enter or esc
+// the App sends every key to the prompt, then runs Submit with the text
+// or Cancel, on the event loop, so they may change the asking pane's
+// state.
+type PromptMsg struct {
+	Label  string // shown before the input, e.g. "Go to line: "
+	Value  string // initial text, with the cursor at its end
+	Submit func(value string) tea.Cmd
+	Cancel func() tea.Cmd // nil does nothing
+}
+
+// askInput returns a command prompting for a line of text, starting from
+// value, and running submit with what was entered
+func askInput(label, value string, submit func(string) tea.Cmd) tea.Cmd {
+	return func() tea.Msg {
+		return PromptMsg{Label: label, Value: value, Submit: submit}
+	}
+}
+
+// inputPrompt is the open prompt and its text input
+type inputPrompt struct {
+	PromptMsg
+	input textinput.Model
+}
+
+// openPrompt shows a prompt, returning the command that starts its cursor
+// blinking
+func (a *App) openPrompt(msg PromptMsg) tea.Cmd {
+	ti := textinput.New()
+	ti.Prompt = msg.Label
+	ti.SetValue(msg.Value)
+	ti.CursorEnd()
+	a.prompt = &inputPrompt{PromptMsg: msg, input: ti}
+	a.sizePrompt()
+	return a.prompt.input.Focus()
+}
+
+// sizePrompt fits the open prompt's input to the window, so long text
+// scrolls instead of wrapping
+func (a *App) sizePrompt() {
+	if a.prompt != nil {
+		a.prompt.input.Width = max(1, a.width-ansi.StringWidth(a.prompt.Label)-1)
+	}
+}
+
+// updatePrompt edits the open prompt, closing it on enter or esc
+func (a *App) updatePrompt(msg tea.KeyMsg) tea.Cmd {
+	p := a.prompt
+	switch msg.String() {
+	case "ctrl+c":
+		return tea.Quit
+	case "esc":
+		a.prompt = nil
+		if p.Cancel == nil {
+			return nil
+		}
+		return p.Cancel()
+	case "enter":
+		a.prompt = nil
+		return p.Submit(p.input.Value())
+	}
+	var cmd tea.Cmd
+	p.input, cmd = p.input.Update(msg)
+	return cmd
+}
+
+// overlayPrompt puts the open prompt over the last line of the frame
+func (a *App) overlayPrompt(frame string) string {
+	lines := strings.Split(frame, "\n")
+	lines[len(lines)-1] = ansi.Truncate(a.prompt.input.View(), a.width, "")
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// renderTimeout bounds how long a headless render waits on any one command,
+// such as a directory read or git blame
+const renderTimeout = 5 * time.Second
+
+// renderFrame runs the app without a terminal: it sizes it, lets startup
+// work settle, feeds the keys in order and returns a single frame
+func renderFrame(app *App, width, height int, keys []tea.KeyMsg) string {
+	app.Update(tea.WindowSizeMsg{Width: width, Height: height})
+	settle(app, app.Init())
+	for _, key := range keys {
+		_, cmd := app.Update(key)
+		settle(app, cmd)
+	}
+	return app.View()
+}
+
+// settle runs cmd and everything that follows from it through m until no
+// work is left. Cursor blinks and quitting are dropped, as a frame neither
+// blinks nor ends.
+func settle(m tea.Model, cmd tea.Cmd) {
+	queue := []tea.Cmd{cmd}
+	for len(queue) > 0 {
+		cmd, queue = queue[0], queue[1:]
+		if cmd == nil {
+			continue
+		}
+		msg := runWithTimeout(cmd)
+		switch msg := msg.(type) {
+		case nil, tea.QuitMsg:
+		case tea.BatchMsg:
+			queue = append(queue, msg...)
+		default:
+			if reflect.TypeOf(msg).PkgPath() == "github.com/charmbracelet/bubbles/cursor" {
+				continue
+			}
+			_, next := m.Update(msg)
+			queue = append(queue, next)
+		}
+	}
+}
+
+func runWithTimeout(cmd tea.Cmd) tea.Msg {
+	done := make(chan tea.Msg, 1)
+	go func() { done <- cmd() }()
+	select {
+	case msg := <-done:
+		return msg
+	case <-time.After(renderTimeout):
+		return nil
+	}
+}
+
+// parseSize reads a "WIDTHxHEIGHT" frame size
+func parseSize(s string) (int, int, error) {
+	w, h, ok := strings.Cut(s, "x")
+	width, err1 := strconv.Atoi(w)
+	height, err2 := strconv.Atoi(h)
+	if !ok || err1 != nil || err2 != nil || width < 1 || height < 1 {
+		return 0, 0, fmt.Errorf("size %q must be WIDTHxHEIGHT, e.g. 120x40", s)
+	}
+	return width, height, nil
+}
+
+// parseKeys reads space-separated key names as shown by bubbletea, e.g.
+// "j j enter tab ctrl+d". Anything else is typed as text; "space" is a
+// space.
+func parseKeys(s string) []tea.KeyMsg {
+	named := make(map[string]tea.KeyType)
+	for k := tea.KeyType(-100); k < 128; k++ {
+		if name := k.String(); name != "" && k != tea.KeyRunes {
+			named[name] = k
+		}
+	}
+	named["space"] = tea.KeySpace
+
+	var keys []tea.KeyMsg
+	for _, field := range strings.Fields(s) {
+		if k, ok := named[field]; ok {
+			keys = append(keys, tea.KeyMsg{Type: k, Runes: keyRunes(k)})
+			continue
+		}
+		for _, r := range field {
+			keys = append(keys, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+		}
+	}
+	return keys
+}
+
+// keyRunes gives the space key its rune, as the terminal reader does
+func keyRunes(k tea.KeyType) []rune {
+	if k == tea.KeySpace {
+		return []rune{' '}
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package main
+
+// defaultScrollOff is how many lines of context are kept around a cursor
+// unless configured otherwise
+const defaultScrollOff = 3
+
+// keepInView returns the scroll offset that shows cursor with at least
+// margin rows of context above and below it, like vim's scrolloff. The
+// margin shrinks on short panes so the cursor can still reach every row.
+func keepInView(cursor, offset, height, total, margin int) int {
+	if height <= 0 {
+		return offset
+	}
+	margin = max(0, min(margin, (height-1)/2))
+	if cursor-margin < offset {
+		offset = cursor - margin
+	}
+	if cursor+margin >= offset+height {
+		offset = cursor + margin - height + 1
+	}
+	return max(0, min(offset, total-height))
+}
+
+// stepCursor moves a list cursor one row by delta, either stopping at the
+// ends or, with wrap, continuing from the other end
+func stepCursor(cursor, delta, total int, wrap bool) int {
+	if total == 0 {
+		return 0
+	}
+	next := cursor + delta
+	switch {
+	case next < 0 && wrap:
+		return total - 1
+	case next >= total && wrap:
+		return 0
+	}
+	return max(0, min(next, total-1))
+}
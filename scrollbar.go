@@ -0,0 +1,61 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// scrollbarStyle draws the scrollbar on the right edge of panes whose
+// content is longer than they are
+type scrollbarStyle struct {
+	show  bool
+	track string // rendered cell for the part outside the viewport
+	thumb string // rendered cell for the part in view
+}
+
+var defaultScrollbar = newScrollbarStyle(DefaultConfig().Scrollbar)
+
+func newScrollbarStyle(cfg ScrollbarConfig) scrollbarStyle {
+	return scrollbarStyle{
+		show:  cfg.Show,
+		track: lipgloss.NewStyle().Foreground(lipgloss.Color(cfg.TrackColor)).Render("│"),
+		thumb: lipgloss.NewStyle().Foreground(lipgloss.Color(cfg.ThumbColor)).Render("┃"),
+	}
+}
+
+// shown reports whether draw puts a scrollbar in a pane of rows rows and
+// width columns showing total rows of content
+func (s scrollbarStyle) shown(rows, width, total int) bool {
+	return s.show && rows >= 1 && total > rows && width >= 2
+}
+
+// draw puts the scrollbar in the last of width columns of lines[top:],
+// for rows rows showing content from offset out of total. Content that
+// fits leaves lines as they are.
+func (s scrollbarStyle) draw(lines []string, top, rows, width, offset, total int) {
+	if !s.shown(rows, width, total) {
+		return
+	}
+	size := max(1, rows*rows/total)
+	start := min(offset*rows/total, rows-size)
+	if offset+rows >= total {
+		start = rows - size // pin to the bottom at the end
+	}
+	for i := range rows {
+		row := top + i
+		if row >= len(lines) {
+			break
+		}
+		cell := s.track
+		if i >= start && i < start+size {
+			cell = s.thumb
+		}
+		// Tabs would be measured as nothing here and expanded to four
+		// cells when the pane is rendered, pushing the bar off the edge
+		line := strings.ReplaceAll(lines[row], "\t", "    ")
+		line = ansi.Truncate(line, width-1, "")
+		lines[row] = line + strings.Repeat(" ", width-1-ansi.StringWidth(line)) + cell
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"strings"
+	"time"
+)
+
+// sortOrder is how the nav and directory listings order entries
+type sortOrder struct {
+	by        dirSort
+	reverse   bool
+	dirsFirst bool // directories before files, whatever the direction
+}
+
+// defaultSortOrder is directories first, then by name, A to Z
+var defaultSortOrder = sortOrder{by: sortByName, dirsFirst: true}
+
+func newSortOrder(cfg SortConfig) sortOrder {
+	by, _ := parseDirSort(cfg.By) // LoadConfig rejects unknown columns
+	return sortOrder{by: by, reverse: cfg.Reverse, dirsFirst: cfg.DirsFirst}
+}
+
+// parseDirSort reads a sort column name from the config
+func parseDirSort(name string) (dirSort, bool) {
+	for _, s := range []dirSort{sortByName, sortBySize, sortByTime} {
+		if s.String() == name {
+			return s, true
+		}
+	}
+	return sortByName, false
+}
+
+// sortKey is what an entry is ordered by
+type sortKey struct {
+	name    string
+	dir     bool
+	size    int64
+	modTime time.Time
+}
+
+// less reports whether a goes before b. Ties on size or time fall back to
+// the name, in the same direction.
+func (o sortOrder) less(a, b sortKey) bool {
+	if o.dirsFirst && a.dir != b.dir {
+		return a.dir
+	}
+	if o.reverse {
+		a, b = b, a
+	}
+	switch o.by {
+	case sortBySize:
+		if a.size != b.size {
+			return a.size < b.size
+		}
+	case sortByTime:
+		if !a.modTime.Equal(b.modTime) {
+			return a.modTime.Before(b.modTime)
+		}
+	}
+	return strings.ToLower(a.name) < strings.ToLower(b.name)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// logName is the log file kept next to config.json; the TUI owns the
+// terminal, so errors worth keeping are logged there
+const logName = "dmc-nav.log"
+
+// dataPath returns the path of a file dmc-nav keeps in the user config
+// directory, next to config.json
+func dataPath(name string) (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "dmc-nav", name), nil
+}
+
+// openLog directs the standard logger to the log file, or discards log
+// output if the file cannot be opened so it never draws over the TUI
+func openLog() io.Closer {
+	log.SetOutput(io.Discard)
+	path, err := dataPath(logName)
+	if err != nil {
+		return io.NopCloser(nil)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return io.NopCloser(nil)
+	}
+	f, err := tea.LogToFile(path, "")
+	if err != nil {
+		return io.NopCloser(nil)
+	}
+	return f
+}
+
+// loadJSON decodes a JSON file into v. A missing file leaves v untouched
+// and is not an error.
+func loadJSON(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// saveJSON writes v as indented JSON, replacing the file atomically so a
+// crash never leaves it half written
+func saveJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// knownFilenames maps well-known files without a telling extension to the
+// language they are written in, "text" for prose
+var knownFilenames = map[string]string{
+	"Makefile":      "make",
+	"GNUmakefile":   "make",
+	"makefile":      "make",
+	"Dockerfile":    "docker",
+	"Containerfile": "docker",
+	"Vagrantfile":   "ruby",
+	"Gemfile":       "ruby",
+	"Rakefile":      "ruby",
+	"Brewfile":      "ruby",
+	"Jenkinsfile":   "groovy",
+	".bashrc":       "bash",
+	".bash_profile": "bash",
+	".profile":      "bash",
+	".zshrc":        "bash",
+	".envrc":        "bash",
+	".gitconfig":    "ini",
+	".editorconfig": "ini",
+	".npmrc":        "ini",
+	".gitignore":    "text",
+	".dockerignore": "text",
+	"LICENSE":       "text",
+	"COPYING":       "text",
+	"AUTHORS":       "text",
+	"NOTICE":        "text",
+	"README":        "text",
+	"CHANGELOG":     "text",
+}
+
+// syntaxTheme is the chroma style the text viewer colors code with
+const syntaxTheme = "monokai"
+
+// filetypeFor returns the language of a file from its name: configured
+// names and patterns first, then the well-known names, else ""
+func filetypeFor(path string, configured map[string]string) string {
+	name := filepath.Base(path)
+	if lang, ok := configured[name]; ok {
+		return lang
+	}
+	patterns := make([]string, 0, len(configured))
+	for pattern := range configured {
+		patterns = append(patterns, pattern)
+	}
+	slices.Sort(patterns)
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return configured[pattern]
+		}
+	}
+	return knownFilenames[name]
+}
+
+// validFiletype reports whether lang names a language that can be
+// highlighted, or is "text"
+func validFiletype(lang string) bool {
+	return lang == "text" || lexers.Get(lang) != nil
+}
+
+// lexerFor picks the lexer for a file of the given language, falling back
+// to chroma's own filename matching. Plain text gets no lexer.
+func lexerFor(path, lang string) chroma.Lexer {
+	var lexer chroma.Lexer
+	switch lang {
+	case "text":
+		return nil
+	case "":
+		lexer = lexers.Match(filepath.Base(path))
+	default:
+		lexer = lexers.Get(lang)
+	}
+	if lexer == nil || lexer.Config().Name == "plaintext" {
+		return nil
+	}
+	return chroma.Coalesce(lexer)
+}
+
+// highlightLine colors one line of code. Lines are lexed on their own, so
+// constructs spanning lines, such as block comments, are only partly
+// colored.
+func highlightLine(lexer chroma.Lexer, line string) string {
+	tokens, err := lexer.Tokenise(nil, line)
+	if err != nil {
+		return line
+	}
+	style := styles.Get(syntaxTheme)
+	plain := style.Get(chroma.Background).Colour // left to the terminal
+	var b strings.Builder
+	for _, tok := range tokens.Tokens() {
+		text := strings.TrimRight(tok.Value, "\n")
+		if text == "" {
+			continue
+		}
+		entry := style.Get(tok.Type)
+		if !entry.Colour.IsSet() || entry.Colour == plain {
+			b.WriteString(text)
+			continue
+		}
+		s := lipgloss.NewStyle().
+			Foreground(lipgloss.Color(entry.Colour.String())).
+			TabWidth(lipgloss.NoTabConversion)
+		if entry.Bold == chroma.Yes {
+			s = s.Bold(true)
+		}
+		b.WriteString(s.Render(text))
+	}
+	return b.String()
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// templateFor returns the template for a new file at path and the mode to
+// create it with. Templates are named by extension in dir, e.g. "go" or
+// "sh"; an executable template makes executable files. {{name}} in a
+// template becomes the file name without extension and {{dir}} the name
+// of its directory, e.g. for a Go package clause. With no template the
+// file starts empty.
+func templateFor(dir, path string) ([]byte, os.FileMode) {
+	ext := strings.TrimPrefix(filepath.Ext(path), ".")
+	if dir == "" || ext == "" {
+		return nil, 0644
+	}
+	tmpl := filepath.Join(dir, ext)
+	info, err := os.Stat(tmpl)
+	if err != nil || !info.Mode().IsRegular() {
+		return nil, 0644
+	}
+	content, err := os.ReadFile(tmpl)
+	if err != nil {
+		return nil, 0644
+	}
+	base := filepath.Base(path)
+	r := strings.NewReplacer(
+		"{{name}}", strings.TrimSuffix(base, filepath.Ext(base)),
+		"{{dir}}", filepath.Base(filepath.Dir(path)),
+	)
+	mode := os.FileMode(0644)
+	if info.Mode()&0111 != 0 {
+		mode = 0755
+	}
+	return []byte(r.Replace(string(content))), mode
+}
+
+// createFile makes a new file from its template, refusing to replace one
+// that exists
+func createFile(path, templateDir string) tea.Cmd {
+	return func() tea.Msg {
+		content, mode := templateFor(templateDir, path)
+		err := writeNewFile(path, content, mode)
+		return FileOpDoneMsg{Op: OpCreate, Results: []OpResult{{Path: path, Err: err}}}
+	}
+}
+
+func writeNewFile(path string, content []byte, mode os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
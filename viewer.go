@@ -1,44 +1,86 @@
 package main
 
 import (
+	"bytes"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/alecthomas/chroma/v2"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
 )
 
 // Viewer is the interface for file content viewers
 type Viewer interface {
 	Pane
+	Name() string // how config refers to the viewer
 	CanView(path string) bool
 	Load(path string) tea.Cmd
 }
 
-// FileLoadedMsg is sent when a file has been loaded
+// FileLoadedMsg is sent when a file has been indexed for viewing
 type FileLoadedMsg struct {
-	Path    string
-	Content string
-	Err     error
+	Path  string
+	Index *lineIndex
+	Shown int64 // bytes of a binary file previewed
+	Err   error
 }
 
 // ViewerRouter selects the appropriate viewer for a file
 type ViewerRouter struct {
 	viewers []Viewer
 	current Viewer
+	path    string // file the current viewer was opened on
+	raw     bool   // the text viewer stands in for the file's own viewer
+	cfg     Config
 	width   int
 	height  int
 	focused bool
+	editing string // file open in the editor, marked in the header
 }
 
-func NewViewerRouter() *ViewerRouter {
-	md := NewMarkdownViewer()
-	jsonv := NewJSONViewer()
-	text := NewTextViewer()
+func NewViewerRouter(cfg Config) *ViewerRouter {
+	md := NewMarkdownViewer(cfg.Markdown)
+	jsonv := NewJSONViewer(cfg.JSON)
+	text := NewTextViewer(cfg.Filetypes)
+	dir := NewDirViewer(cfg.Nav.Hide, cfg.Format, newSortOrder(cfg.Sort))
+	kv := NewKeyValueViewer()
+	hexv := NewHexViewer()
+	dir.SetScrollOff(cfg.ScrollOff)
+	md.SetScrollOff(cfg.ScrollOff)
+	jsonv.SetScrollOff(cfg.ScrollOff)
+	text.SetScrollOff(cfg.ScrollOff)
+	kv.SetScrollOff(cfg.ScrollOff)
+	cursor := newCursorStyle(cfg.Cursor)
+	dir.SetCursorStyle(cursor)
+	md.SetCursorStyle(cursor)
+	jsonv.SetCursorStyle(cursor)
+	jsonv.SetWrapAround(cfg.WrapAround)
+	caseMode, _ := parseCaseMode(cfg.Search.Case)
+	jsonv.SetCaseMode(caseMode)
+	text.SetCursorStyle(cursor)
+	kv.SetCursorStyle(cursor)
+	bar := newScrollbarStyle(cfg.Scrollbar)
+	dir.SetScrollbar(bar)
+	md.SetScrollbar(bar)
+	jsonv.SetScrollbar(bar)
+	text.SetScrollbar(bar)
+	kv.SetScrollbar(bar)
+	hexv.SetScrollbar(bar)
+	badge := newFormatBadge(cfg.Encoding)
+	text.SetFormatBadge(badge)
+	kv.SetFormatBadge(badge)
+	text.SetPrefetch(cfg.Prefetch)
+	text.SetBinaryPreview(cfg.BinaryPreview)
+	kv.SetPrefetch(cfg.Prefetch)
 	return &ViewerRouter{
-		viewers: []Viewer{md, jsonv, text}, // order matters: specific viewers before fallback
+		viewers: []Viewer{dir, md, jsonv, kv, hexv, text}, // order matters: specific viewers before fallback
 		current: text,
+		cfg:     cfg,
 	}
 }
 
@@ -50,6 +92,37 @@ func (r *ViewerRouter) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	if r.current == nil {
 		return r, nil
 	}
+	if key, ok := msg.(tea.KeyMsg); ok && r.focused {
+		// r on the screen of an error a retry may get past loads the file
+		// again, whatever r does otherwise
+		if key.String() == "r" {
+			if f, ok := r.current.(interface{ FailedLoad() (string, error) }); ok {
+				if path, err := f.FailedLoad(); path != "" && isTransient(err) {
+					return r, r.current.Load(path)
+				}
+			}
+		}
+		// Keys every viewer shares, unless it has its own use for them
+		if !r.ClaimsKey(key.String()) {
+			switch key.String() {
+			case "r":
+				if r.modified() {
+					return r, askConfirm("discard", "Discard unsaved changes and reload?", r.reload, nil)
+				}
+				return r, r.reload()
+			case "m":
+				if r.modified() {
+					return r, askConfirm("discard", "Discard unsaved changes?", func() tea.Cmd {
+						cmd, _ := r.toggleFormat()
+						return cmd
+					}, nil)
+				}
+				if cmd, ok := r.toggleFormat(); ok {
+					return r, cmd
+				}
+			}
+		}
+	}
 	m, cmd := r.current.Update(msg)
 	r.current = m.(Viewer)
 	return r, cmd
@@ -59,15 +132,50 @@ func (r *ViewerRouter) View() string {
 	if r.current == nil {
 		return "No viewer"
 	}
-	return r.current.View()
+	view := r.current.View()
+	if r.editing != "" && r.editing == r.path {
+		view = withEditingMark(view, r.widthFor(r.current))
+	}
+	margin := (r.width - r.widthFor(r.current)) / 2
+	if margin == 0 {
+		return view
+	}
+	pad := strings.Repeat(" ", margin)
+	return pad + strings.ReplaceAll(view, "\n", "\n"+pad)
+}
+
+// withEditingMark ends a viewer's header with a note that its file is
+// open in the editor, cutting the header short if there is no room
+func withEditingMark(view string, width int) string {
+	header, rest, _ := strings.Cut(view, "\n")
+	mark := lipgloss.NewStyle().Foreground(lipgloss.Color("214")).Render(" ✎ editing")
+	header = ansi.Truncate(strings.TrimRight(header, " "), max(0, width-ansi.StringWidth(mark)), "")
+	return header + mark + "\n" + rest
+}
+
+// SetEditing marks the file open in the editor, "" for none
+func (r *ViewerRouter) SetEditing(path string) {
+	r.editing = path
 }
 
 func (r *ViewerRouter) SetSize(width, height int) {
 	r.width = width
 	r.height = height
 	for _, v := range r.viewers {
-		v.SetSize(width, height)
+		v.SetSize(r.widthFor(v), height)
+	}
+}
+
+// widthFor returns the width v draws in: the pane width, or for viewers
+// of prose the configured maximum when the pane is wider
+func (r *ViewerRouter) widthFor(v Viewer) int {
+	switch v.Name() {
+	case "text", "keyvalue", "markdown":
+		if r.cfg.MaxWidth > 0 {
+			return min(r.width, r.cfg.MaxWidth)
+		}
 	}
+	return r.width
 }
 
 func (r *ViewerRouter) Focused() bool {
@@ -81,37 +189,215 @@ func (r *ViewerRouter) SetFocused(focused bool) {
 	}
 }
 
-// OpenFile selects appropriate viewer and loads the file
-func (r *ViewerRouter) OpenFile(path string) tea.Cmd {
-	// Find first viewer that can handle this file
+// Close lets viewers save what they keep between sessions
+func (r *ViewerRouter) Close() {
 	for _, v := range r.viewers {
-		if v.CanView(path) {
-			r.current = v
-			r.current.SetSize(r.width, r.height)
-			r.current.SetFocused(r.focused)
-			return r.current.Load(path)
+		if c, ok := v.(interface{ Close() }); ok {
+			c.Close()
 		}
 	}
-	// Fallback to first viewer (text)
-	r.current = r.viewers[0]
+}
+
+// SetSavedJSONViews sets the JSON views remembered from earlier sessions
+// and the file they are saved to
+func (r *ViewerRouter) SetSavedJSONViews(views map[string]savedJSONView, path string) {
+	for _, v := range r.viewers {
+		if j, ok := v.(*JSONViewer); ok {
+			j.SetSavedViews(views, path)
+		}
+	}
+}
+
+// Rerender lets the current viewer redo width-dependent rendering after a
+// resize; viewers that render width-independently are skipped
+func (r *ViewerRouter) Rerender() tea.Cmd {
+	if v, ok := r.current.(interface{ Rerender() tea.Cmd }); ok {
+		return v.Rerender()
+	}
+	return nil
+}
+
+// EditLine returns the 1-based line the current viewer wants the editor
+// opened at, or 0 for the top
+func (r *ViewerRouter) EditLine() int {
+	if v, ok := r.current.(interface{ EditLine() int }); ok {
+		return v.EditLine()
+	}
+	return 0
+}
+
+// CapturingInput reports whether the current viewer is reading a prompt
+func (r *ViewerRouter) CapturingInput() bool {
+	c, ok := r.current.(inputCapturer)
+	return ok && c.CapturingInput()
+}
+
+// ClaimsKey reports whether the current viewer wants a key the App would
+// otherwise handle
+func (r *ViewerRouter) ClaimsKey(key string) bool {
+	c, ok := r.current.(keyClaimer)
+	return ok && c.ClaimsKey(key)
+}
+
+// OpenFile selects appropriate viewer and loads the file, scrolled to a
+// 1-based line where the viewer supports it
+func (r *ViewerRouter) OpenFile(path string, line int) tea.Cmd {
+	return r.open(r.viewerFor(path), path, line)
+}
+
+// OpenHex loads a file into the hex viewer, whatever its type
+func (r *ViewerRouter) OpenHex(path string) tea.Cmd {
+	return r.open(r.viewerNamed("hex"), path, 0)
+}
+
+func (r *ViewerRouter) open(v Viewer, path string, line int) tea.Cmd {
+	r.path = path
+	r.raw = false
+	r.current = v
+	r.current.SetSize(r.widthFor(v), r.height)
+	r.current.SetFocused(r.focused)
+	if s, ok := v.(interface{ SeekLine(line int) }); ok {
+		s.SeekLine(line)
+	}
 	return r.current.Load(path)
 }
 
-// TextViewer displays plain text files
+// viewerNamed returns the viewer config calls name
+func (r *ViewerRouter) viewerNamed(name string) Viewer {
+	for _, v := range r.viewers {
+		if v.Name() == name {
+			return v
+		}
+	}
+	return r.viewers[len(r.viewers)-1]
+}
+
+// modified reports whether the current viewer holds edits not yet saved,
+// which reading the file again would throw away
+func (r *ViewerRouter) modified() bool {
+	v, ok := r.current.(interface{ Modified() bool })
+	return ok && v.Modified()
+}
+
+// reload reads the current file again, keeping the reader's place where
+// the viewer can
+func (r *ViewerRouter) reload() tea.Cmd {
+	if r.path == "" {
+		return nil
+	}
+	if v, ok := r.current.(interface{ Reload() tea.Cmd }); ok {
+		return v.Reload()
+	}
+	return r.current.Load(r.path)
+}
+
+// toggleFormat switches between the formatted view of the file and the
+// file as written. Viewers with a plain mode of their own switch it;
+// others swap places with the text viewer. It reports false when there
+// is nothing to switch.
+func (r *ViewerRouter) toggleFormat() (tea.Cmd, bool) {
+	if v, ok := r.current.(interface{ ToggleFormat() tea.Cmd }); ok {
+		return v.ToggleFormat(), true
+	}
+	if r.path == "" {
+		return nil, false
+	}
+	if r.raw {
+		return r.open(r.viewerFor(r.path), r.path, 0), true
+	}
+	switch r.current.Name() {
+	case "text", "dir", "hex":
+		return nil, false
+	}
+	cmd := r.open(r.viewerNamed("text"), r.path, 0)
+	r.raw = true
+	return cmd, true
+}
+
+// viewerFor picks the viewer configured for the file's extension, or else
+// the first that can view it
+func (r *ViewerRouter) viewerFor(path string) Viewer {
+	// Compressed files are viewed by the name of what they hold
+	inner := innerPath(path)
+	name := r.cfg.ViewerFor(inner)
+	// A directory is listed whatever its name says, so "notes.md/" is
+	// never read as markdown
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		name = "dir"
+	}
+	if name != "" {
+		for _, v := range r.viewers {
+			if v.Name() == name {
+				return v
+			}
+		}
+	}
+	for _, v := range r.viewers {
+		if v.CanView(inner) {
+			return v
+		}
+	}
+	// Unreachable while TextViewer views everything
+	return r.viewers[len(r.viewers)-1]
+}
+
+// textWindowLines is how many lines TextViewer keeps in memory around the
+// viewport; lines outside the window are re-read from disk on demand.
+const textWindowLines = 1024
+
+// defaultPrefetch is how near an edge of the window reading ahead starts
+const defaultPrefetch = 256
+
+// TextViewer displays plain text files. Files are not loaded whole: Load
+// indexes line offsets and only a window of lines around the viewport is
+// read, so very large files scroll without being held in memory.
 type TextViewer struct {
 	width   int
 	height  int
 	focused bool
 
-	path    string
-	content string
-	lines   []string
-	offset  int
-	err     error
+	path        string
+	index       *lineIndex
+	window      []string // lines currently read from disk
+	windowStart int      // line number of window[0]
+	offset      int
+	err         error
+
+	filetypes map[string]string   // configured languages by file name
+	lexer     chroma.Lexer        // highlighter for the file, nil for plain text
+	styleLine func(string) string // replaces highlighting in viewers built on this one
+
+	invisibles int // invisiblesOff, invisiblesShown or invisiblesSpaces
+
+	showBlame bool                      // blame gutter toggled on
+	blames    map[string]BlameLoadedMsg // git blame per file, while unchanged
+
+	startLine   int // line to scroll to once the next load arrives
+	scrollOff   int
+	cursorStyle cursorStyle // also marks the visual selection
+	scrollbar   scrollbarStyle
+	formatBadge formatBadge
+
+	prefetch    int  // lines from an edge of the window at which the next is read ahead
+	prefetching bool // a read-ahead is running
+
+	binaryPreview int   // bytes of a binary file shown
+	shown         int64 // bytes shown of a binary file, 0 for a text file
+
+	visual bool // line selection active
+	anchor int  // line where the selection started
+	cursor int  // line the selection extends to
+	status string
 }
 
-func NewTextViewer() *TextViewer {
-	return &TextViewer{}
+func NewTextViewer(filetypes map[string]string) *TextViewer {
+	return &TextViewer{
+		filetypes:     filetypes,
+		blames:        make(map[string]BlameLoadedMsg),
+		cursorStyle:   defaultCursor,
+		scrollbar:     defaultScrollbar,
+		binaryPreview: defaultBinaryPreview,
+	}
 }
 
 func (t *TextViewer) Init() tea.Cmd {
@@ -122,17 +408,73 @@ func (t *TextViewer) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case FileLoadedMsg:
 		if msg.Path == t.path {
-			t.content = msg.Content
-			t.lines = strings.Split(msg.Content, "\n")
+			t.index = msg.Index
+			t.window = nil
+			t.windowStart = 0
+			t.shown = msg.Shown
+			if msg.Shown > 0 {
+				t.lexer = nil // escapes are not source
+			}
 			t.offset = 0
 			t.err = msg.Err
+			if t.startLine > 0 {
+				// Show the line a little below the top, for context
+				t.scroll(t.startLine - 1 - t.height/4)
+				t.startLine = 0
+			}
+			t.visual = false
+			t.status = ""
+			t.prefetching = false
+			t.ensureWindow()
+			if t.showBlame && t.err == nil {
+				return t, t.blameCmd()
+			}
+		}
+
+	case BlameLoadedMsg:
+		t.blames[msg.Path] = msg
+		if msg.Path == t.path && t.showBlame {
+			t.status = ""
+			if msg.Err != nil {
+				t.status = "No blame: " + msg.Err.Error()
+			}
 		}
 
+	case ClipboardMsg:
+		t.status = msg.Status()
+
+	case TextWindowMsg:
+		return t, t.windowRead(msg)
+
 	case tea.KeyMsg:
 		if !t.focused {
 			return t, nil
 		}
+		t.status = ""
+		if t.visual {
+			cmd := t.updateVisual(msg)
+			t.ensureWindow()
+			return t, tea.Batch(cmd, t.prefetchWindow())
+		}
 		switch msg.String() {
+		case "Y":
+			if t.path != "" {
+				return t, yankFile(t.path)
+			}
+		case "i":
+			t.invisibles = (t.invisibles + 1) % 3
+			t.status = [...]string{"Invisibles hidden", "Showing invisibles", "Showing invisibles and spaces"}[t.invisibles]
+		case "b":
+			t.showBlame = !t.showBlame
+			if t.showBlame {
+				return t, t.blameCmd()
+			}
+		case "V":
+			if t.lineCount() > 0 {
+				t.visual = true
+				t.anchor = t.offset
+				t.cursor = t.offset
+			}
 		case "j", "down":
 			t.scroll(1)
 		case "k", "up":
@@ -144,8 +486,12 @@ func (t *TextViewer) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "g":
 			t.offset = 0
 		case "G":
-			t.offset = max(0, len(t.lines)-t.height+2)
+			t.offset = max(0, t.lineCount()-t.height+2)
+		case "%":
+			t.jumpBracket()
 		}
+		t.ensureWindow()
+		return t, t.prefetchWindow()
 	}
 
 	return t, nil
@@ -156,29 +502,73 @@ func (t *TextViewer) View() string {
 		return t.centerText("Select a file to view")
 	}
 	if t.err != nil {
-		return t.centerText("Error: " + t.err.Error())
+		return t.centerText(loadErrorText(t.err))
 	}
 
 	var visible []string
 	end := t.offset + t.height - 1
-	if end > len(t.lines) {
-		end = len(t.lines)
+	if end > t.lineCount() {
+		end = t.lineCount()
 	}
 
+	gutterStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
+	first, last := t.selection()
+	blame := t.blame()
+	textWidth := t.width
+	if blame != nil {
+		textWidth -= blameWidth
+	}
+	longest := 0 // widest line cut off in view
 	for i := t.offset; i < end; i++ {
-		line := t.lines[i]
-		// Truncate long lines
-		if len(line) > t.width-2 {
-			line = line[:t.width-5] + "..."
+		line := t.line(i)
+		selected := t.visual && i >= first && i <= last
+		switch {
+		case t.invisibles != invisiblesOff:
+			// Glyphs replace highlighting, which would hide them
+			line = showInvisibles(line, t.invisibles == invisiblesSpaces)
+		case t.styleLine != nil && !selected:
+			line = t.styleLine(line)
+		case t.lexer != nil && !selected:
+			line = highlightLine(t.lexer, line)
+		}
+		w := ansi.StringWidth(line)
+		if w > textWidth-2 {
+			longest = max(longest, w)
+			line = clipLine(line, textWidth-2)
+		}
+		if selected {
+			line = t.cursorStyle.render(line, textWidth-2)
+		}
+		if blame != nil {
+			var b blameLine
+			if i < len(blame) {
+				b = blame[i]
+			}
+			line = gutterStyle.Render(b.render()) + line
 		}
 		visible = append(visible, line)
 	}
 
-	// Header with filename
+	// Header with filename, and the encoding at the right
 	header := lipgloss.NewStyle().
 		Bold(true).
 		Foreground(lipgloss.Color("12")).
 		Render(filepath.Base(t.path))
+	header += gutterStyle.Render(compressedNote(t.path))
+	if t.shown > 0 {
+		notice := "  binary, escaped"
+		if t.shown < t.index.size {
+			notice = fmt.Sprintf("  binary, first %s of %s", humanSize(t.shown), humanSize(t.index.size))
+		}
+		header += gutterStyle.Render(notice)
+	}
+	if t.index != nil {
+		// Not until the first file has loaded
+		if badge := t.formatBadge.render(t.index.format); badge != "" {
+			gap := max(1, t.width-ansi.StringWidth(header)-ansi.StringWidth(badge))
+			header += strings.Repeat(" ", gap) + badge
+		}
+	}
 
 	lines := append([]string{header}, visible...)
 
@@ -186,13 +576,168 @@ func (t *TextViewer) View() string {
 	for len(lines) < t.height {
 		lines = append(lines, "")
 	}
+	t.scrollbar.draw(lines, 1, t.height-2, t.width, t.offset, t.lineCount())
+
+	// Selection and copy messages take over the last line
+	if footer := t.footer(longest); footer != "" && len(lines) > 1 {
+		lines[len(lines)-1] = footer
+	}
 
 	return strings.Join(lines, "\n")
 }
 
+// footer shows the status or visual selection, otherwise how far the
+// longest cut-off line in view runs
+func (t *TextViewer) footer(longest int) string {
+	style := lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
+	switch {
+	case t.status != "":
+		return style.Render(t.status)
+	case t.visual:
+		first, last := t.selection()
+		return style.Render(fmt.Sprintf("-- VISUAL -- lines %d-%d (y: copy, e: edit, esc: cancel)", first+1, last+1))
+	case longest > 0:
+		return overflowMarker + style.Render(fmt.Sprintf(" longest line here is %d columns", longest))
+	}
+	return ""
+}
+
+// blame returns the blame for the current file when the gutter is on and
+// blame is available
+func (t *TextViewer) blame() []blameLine {
+	if !t.showBlame {
+		return nil
+	}
+	b, ok := t.blames[t.path]
+	if !ok || b.Err != nil {
+		return nil
+	}
+	return b.Lines
+}
+
+// blameCmd runs git blame for the current file unless a result for its
+// current content is cached
+func (t *TextViewer) blameCmd() tea.Cmd {
+	if t.path == "" {
+		return nil
+	}
+	if b, ok := t.blames[t.path]; ok {
+		if info, err := os.Stat(t.path); err == nil && info.ModTime().Equal(b.ModTime) {
+			if b.Err != nil {
+				t.status = "No blame: " + b.Err.Error()
+			}
+			return nil
+		}
+	}
+	t.status = "Running git blame…"
+	return loadBlame(t.path)
+}
+
+// selection returns the first and last selected lines
+func (t *TextViewer) selection() (int, int) {
+	return min(t.anchor, t.cursor), max(t.anchor, t.cursor)
+}
+
+// updateVisual handles keys while selecting lines: movement extends the
+// selection and y copies it
+func (t *TextViewer) updateVisual(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "j", "down":
+		t.moveCursor(1)
+	case "k", "up":
+		t.moveCursor(-1)
+	case "d", "ctrl+d":
+		t.moveCursor(t.height / 2)
+	case "u", "ctrl+u":
+		t.moveCursor(-t.height / 2)
+	case "g":
+		t.moveCursor(-t.cursor)
+	case "G":
+		t.moveCursor(t.lineCount())
+	case "%":
+		t.jumpBracket()
+	case "o":
+		// Swap ends to extend the selection the other way
+		t.anchor, t.cursor = t.cursor, t.anchor
+		t.moveCursor(0)
+	case "esc", "V", "q":
+		t.visual = false
+	case "y", "Y":
+		t.visual = false
+		first, last := t.selection()
+		lines, err := t.index.readLines(t.path, first, last+1)
+		if err != nil {
+			t.status = "Copy failed: " + err.Error()
+			return nil
+		}
+		return yankLines(lines, first, last)
+	}
+	return nil
+}
+
+// ClaimsKey keeps "q" for leaving visual mode while it is on, rather
+// than quitting
+func (t *TextViewer) ClaimsKey(key string) bool {
+	return key == "q" && t.visual
+}
+
+// EditLine returns the 1-based line the editor should open at: the start
+// of the selection, which is then cleared, or 0 when nothing is selected
+func (t *TextViewer) EditLine() int {
+	if !t.visual {
+		return 0
+	}
+	t.visual = false
+	first, _ := t.selection()
+	return first + 1
+}
+
+// moveCursor moves the selection end, scrolling to keep it in view
+func (t *TextViewer) moveCursor(delta int) {
+	t.cursor = max(0, min(t.cursor+delta, t.lineCount()-1))
+	t.offset = keepInView(t.cursor, t.offset, max(1, t.height-2), t.lineCount(), t.scrollOff)
+}
+
+// SetScrollOff sets the rows of context kept around the selection end
+func (t *TextViewer) SetScrollOff(lines int) {
+	t.scrollOff = lines
+}
+
+// SetScrollbar sets how the scrollbar is drawn
+func (t *TextViewer) SetScrollbar(style scrollbarStyle) {
+	t.scrollbar = style
+}
+
+// SetPrefetch sets how close to an edge of the lines in memory the
+// viewport may come before the next window is read in the background; 0
+// reads only when lines are needed
+func (t *TextViewer) SetPrefetch(lines int) {
+	t.prefetch = lines
+}
+
+// SetBinaryPreview sets how many bytes of a binary file are shown
+func (t *TextViewer) SetBinaryPreview(bytes int) {
+	t.binaryPreview = bytes
+}
+
+// SetFormatBadge sets whether and how the header shows the file's
+// encoding and line endings
+func (t *TextViewer) SetFormatBadge(badge formatBadge) {
+	t.formatBadge = badge
+}
+
+// SetCursorStyle sets how lines in the visual selection are drawn
+func (t *TextViewer) SetCursorStyle(style cursorStyle) {
+	t.cursorStyle = style
+}
+
 func (t *TextViewer) SetSize(width, height int) {
+	if width == t.width && height == t.height {
+		return
+	}
 	t.width = width
 	t.height = height
+	t.ensureWindow()
 }
 
 func (t *TextViewer) Focused() bool {
@@ -203,6 +748,33 @@ func (t *TextViewer) SetFocused(focused bool) {
 	t.focused = focused
 }
 
+// FailedLoad returns the file whose load failed and why, or "" and nil
+// when it did not
+func (t *TextViewer) FailedLoad() (string, error) {
+	if t.err == nil {
+		return "", nil
+	}
+	return t.path, t.err
+}
+
+// Reload reads the file again, scrolled to where it is now
+func (t *TextViewer) Reload() tea.Cmd {
+	if t.path == "" {
+		return nil
+	}
+	t.startLine = t.offset + 1 + t.height/4 // undoes the context FileLoadedMsg leaves
+	return t.Load(t.path)
+}
+
+// SeekLine sets the 1-based line the next loaded file is scrolled to
+func (t *TextViewer) SeekLine(line int) {
+	t.startLine = line
+}
+
+func (t *TextViewer) Name() string {
+	return "text"
+}
+
 func (t *TextViewer) CanView(path string) bool {
 	// TextViewer is the fallback, handles everything
 	return true
@@ -210,14 +782,126 @@ func (t *TextViewer) CanView(path string) bool {
 
 func (t *TextViewer) Load(path string) tea.Cmd {
 	t.path = path
+	inner := innerPath(path)
+	t.lexer = lexerFor(inner, filetypeFor(inner, t.filetypes))
+	limit := t.binaryPreview
 	return func() tea.Msg {
-		content, err := os.ReadFile(path)
+		if err := checkSpecial(path); err != nil {
+			return FileLoadedMsg{Path: path, Err: err}
+		}
+		if compressionOf(path) != "" {
+			return decompressedLines(path, limit)
+		}
+		if looksBinary(path) {
+			return previewBinary(path, limit)
+		}
+		index, err := indexLines(path)
 		return FileLoadedMsg{
-			Path:    path,
-			Content: string(content),
-			Err:     err,
+			Path:  path,
+			Index: index,
+			Err:   err,
+		}
+	}
+}
+
+// lineCount returns the total number of lines in the indexed file
+func (t *TextViewer) lineCount() int {
+	if t.index == nil {
+		return 0
+	}
+	return t.index.count()
+}
+
+// line returns line i if it is inside the loaded window
+func (t *TextViewer) line(i int) string {
+	if i < t.windowStart || i >= t.windowStart+len(t.window) {
+		return ""
+	}
+	return t.window[i-t.windowStart]
+}
+
+// ensureWindow reads a new window of lines from disk when the viewport is
+// not fully covered by the current one
+func (t *TextViewer) ensureWindow() {
+	if t.index == nil || t.err != nil {
+		return
+	}
+	first := t.offset
+	last := min(t.offset+t.height, t.lineCount())
+	if first >= t.windowStart && last <= t.windowStart+len(t.window) {
+		return
+	}
+
+	start, end := t.windowAround(first)
+	lines, err := t.index.readLines(t.path, start, end)
+	if err != nil {
+		t.err = err
+		return
+	}
+	t.window = lines
+	t.windowStart = start
+}
+
+// windowAround returns the lines to keep in memory for a viewport starting
+// at first, centered on it so scrolling either way stays cheap
+func (t *TextViewer) windowAround(first int) (start, end int) {
+	windowSize := max(textWindowLines, t.height*2)
+	start = max(0, first-(windowSize-t.height)/2)
+	end = min(start+windowSize, t.lineCount())
+	return start, end
+}
+
+// TextWindowMsg carries a window of lines read ahead in the background
+type TextWindowMsg struct {
+	Path  string
+	Index *lineIndex // the index read with, so reads from an older load are dropped
+	Start int
+	Lines []string // nil if the read failed
+}
+
+// prefetchWindow reads the window around the viewport in the background
+// once the viewport comes within prefetch lines of an edge of the one in
+// memory, so scrolling rarely has to wait for a read
+func (t *TextViewer) prefetchWindow() tea.Cmd {
+	if t.prefetch <= 0 || t.prefetching || t.index == nil || t.err != nil {
+		return nil
+	}
+	windowEnd := t.windowStart + len(t.window)
+	// Well inside the edges, so a freshly centered window is never near one
+	margin := min(t.prefetch, (max(textWindowLines, t.height*2)-t.height)/4)
+	nearEnd := windowEnd < t.lineCount() && t.offset+t.height+margin > windowEnd
+	nearStart := t.windowStart > 0 && t.offset-margin < t.windowStart
+	if !nearEnd && !nearStart {
+		return nil
+	}
+	start, end := t.windowAround(t.offset)
+	if start == t.windowStart && end == windowEnd {
+		return nil
+	}
+	t.prefetching = true
+	path, index := t.path, t.index
+	return func() tea.Msg {
+		lines, err := index.readLines(path, start, end)
+		if err != nil {
+			lines = nil // a read when the lines are needed reports it
 		}
+		return TextWindowMsg{Path: path, Index: index, Start: start, Lines: lines}
+	}
+}
+
+// windowRead takes a read-ahead window if it still covers the viewport
+func (t *TextViewer) windowRead(msg TextWindowMsg) tea.Cmd {
+	if msg.Index != t.index || msg.Path != t.path {
+		return nil // a read for an earlier load, which may still be running
 	}
+	t.prefetching = false
+	last := min(t.offset+t.height, t.lineCount())
+	if msg.Lines == nil || t.offset < msg.Start || last > msg.Start+len(msg.Lines) {
+		return nil
+	}
+	t.window = msg.Lines
+	t.windowStart = msg.Start
+	return t.prefetchWindow()
 }
 
 func (t *TextViewer) scroll(delta int) {
@@ -225,7 +909,7 @@ func (t *TextViewer) scroll(delta int) {
 	if t.offset < 0 {
 		t.offset = 0
 	}
-	maxOffset := len(t.lines) - t.height + 2
+	maxOffset := t.lineCount() - t.height + 2
 	if maxOffset < 0 {
 		maxOffset = 0
 	}
@@ -234,6 +918,21 @@ func (t *TextViewer) scroll(delta int) {
 	}
 }
 
+// overflowMarker ends a line that continues past the right edge
+var overflowMarker = lipgloss.NewStyle().Foreground(lipgloss.Color("214")).Bold(true).Render("›")
+
+// clipLine cuts a possibly styled line to width cells, the last of them
+// taken by the overflow marker
+func clipLine(line string, width int) string {
+	if ansi.StringWidth(line) <= width {
+		return line
+	}
+	if width < 1 {
+		return ""
+	}
+	return ansi.Truncate(line, width-1, "") + overflowMarker
+}
+
 func (t *TextViewer) centerText(text string) string {
 	style := lipgloss.NewStyle().
 		Width(t.width).
@@ -241,3 +940,95 @@ func (t *TextViewer) centerText(text string) string {
 		Align(lipgloss.Center, lipgloss.Center)
 	return style.Render(text)
 }
+
+// lineIndex records the byte offset at which each line of a file starts,
+// or holds the lines themselves when what is shown is not the file as it
+// is on disk
+type lineIndex struct {
+	offsets []int64
+	lines   []string // read from instead of the file when not nil
+	size    int64
+	format  fileFormat
+}
+
+// heldIndex indexes lines held in memory, standing for size bytes of a
+// file in format
+func heldIndex(lines []string, size int64, format fileFormat) *lineIndex {
+	return &lineIndex{lines: lines, size: size, format: format}
+}
+
+// indexLines scans a file once, recording where each line starts, without
+// keeping its content
+func indexLines(path string) (*lineIndex, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	idx := &lineIndex{offsets: []int64{0}}
+	buf := make([]byte, 64*1024)
+	var pos int64
+	var format formatScanner
+	for {
+		n, err := f.Read(buf)
+		chunk := buf[:n]
+		format.scan(chunk)
+		for {
+			i := bytes.IndexByte(chunk, '\n')
+			if i < 0 {
+				break
+			}
+			pos += int64(i) + 1
+			idx.offsets = append(idx.offsets, pos)
+			chunk = chunk[i+1:]
+		}
+		pos += int64(len(chunk))
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	idx.size = pos
+	idx.format = format.format()
+	return idx, nil
+}
+
+// count returns the number of lines, counting a trailing empty line after a
+// final newline the same way strings.Split does
+func (idx *lineIndex) count() int {
+	if idx.lines != nil {
+		return len(idx.lines)
+	}
+	return len(idx.offsets)
+}
+
+// readLines reads lines [start, end) from the file, or those held, without
+// their newlines
+func (idx *lineIndex) readLines(path string, start, end int) ([]string, error) {
+	if start >= end {
+		return nil, nil
+	}
+	if idx.lines != nil {
+		return idx.lines[start:end:end], nil
+	}
+	from := idx.offsets[start]
+	to := idx.size
+	if end < len(idx.offsets) {
+		to = idx.offsets[end] - 1 // drop the newline ending line end-1
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data := make([]byte, to-from)
+	if _, err := f.ReadAt(data, from); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return strings.Split(string(data), "\n"), nil
+}
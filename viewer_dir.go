@@ -0,0 +1,340 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// dirItem is one row of a directory listing
+type dirItem struct {
+	Name    string
+	IsDir   bool
+	Size    int64
+	ModTime time.Time
+	Mode    os.FileMode
+	Link    string // symlink target, "" if not a link
+}
+
+// dirSort is the column a listing is ordered by
+type dirSort int
+
+const (
+	sortByName dirSort = iota
+	sortBySize
+	sortByTime
+)
+
+func (s dirSort) String() string {
+	switch s {
+	case sortBySize:
+		return "size"
+	case sortByTime:
+		return "modified"
+	}
+	return "name"
+}
+
+// DirListedMsg is sent when a directory has been listed with metadata
+type DirListedMsg struct {
+	Path  string
+	Items []dirItem
+	Err   error
+}
+
+// DirViewer shows a directory as a detailed, sortable listing, like the
+// second pane of a two-pane file manager
+type DirViewer struct {
+	width   int
+	height  int
+	focused bool
+
+	hide        []string // names left out, as in the nav
+	scrollOff   int
+	cursorStyle cursorStyle
+	scrollbar   scrollbarStyle
+
+	path   string
+	items  []dirItem
+	cursor int
+	offset int
+	order  sortOrder
+	err    error
+
+	exactSizes    bool // byte counts instead of rounded sizes
+	relativeTimes bool // "3 hours ago" instead of timestamps
+}
+
+func NewDirViewer(hide []string, format FormatConfig, order sortOrder) *DirViewer {
+	return &DirViewer{
+		hide:          hide,
+		order:         order,
+		cursorStyle:   defaultCursor,
+		scrollbar:     defaultScrollbar,
+		exactSizes:    format.Sizes == "bytes",
+		relativeTimes: format.Times == "relative",
+	}
+}
+
+func (d *DirViewer) Init() tea.Cmd {
+	return nil
+}
+
+func (d *DirViewer) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case DirListedMsg:
+		if msg.Path == d.path {
+			d.items = msg.Items
+			d.err = msg.Err
+			d.cursor = 0
+			d.offset = 0
+			d.sortItems()
+		}
+
+	case tea.KeyMsg:
+		if !d.focused {
+			return d, nil
+		}
+		switch msg.String() {
+		case "j", "down":
+			d.moveCursor(1)
+		case "k", "up":
+			d.moveCursor(-1)
+		case "d", "ctrl+d":
+			d.moveCursor(d.height / 2)
+		case "u", "ctrl+u":
+			d.moveCursor(-d.height / 2)
+		case "g":
+			d.moveCursor(-d.cursor)
+		case "G":
+			d.moveCursor(len(d.items))
+		case "s":
+			d.order.by = (d.order.by + 1) % 3
+			d.sortItems()
+		case "r":
+			d.order.reverse = !d.order.reverse
+			d.sortItems()
+		case "f":
+			d.order.dirsFirst = !d.order.dirsFirst
+			d.sortItems()
+		case "b":
+			d.exactSizes = !d.exactSizes
+		case "t":
+			d.relativeTimes = !d.relativeTimes
+		case "h", "backspace", "left":
+			if parent := filepath.Dir(d.path); parent != d.path {
+				return d, d.Load(parent)
+			}
+		case "enter", "l", "right":
+			if d.cursor >= len(d.items) {
+				return d, nil
+			}
+			item := d.items[d.cursor]
+			path := filepath.Join(d.path, item.Name)
+			if item.IsDir {
+				return d, d.Load(path)
+			}
+			return d, func() tea.Msg {
+				return FileSelectedMsg{Path: path}
+			}
+		}
+	}
+
+	return d, nil
+}
+
+func (d *DirViewer) View() string {
+	if d.path == "" {
+		return d.centerText("Select a directory to view")
+	}
+	if d.err != nil {
+		return d.centerText(loadErrorText(d.err))
+	}
+
+	header := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("12")).
+		Render(ansi.Truncate(d.path, d.width, "…"))
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
+	lines := []string{header, dim.Render(d.formatRow("Mode", "Size", "Modified", "Name"))}
+
+	dirStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("12"))
+	end := min(d.offset+d.listHeight(), len(d.items))
+	now := time.Now()
+	for i := d.offset; i < end; i++ {
+		item := d.items[i]
+		size := formatSize(item.Size, d.exactSizes)
+		name := item.Name
+		if item.IsDir {
+			size = "-"
+			name += "/"
+		}
+		if item.Link != "" {
+			name += " -> " + item.Link
+		}
+		line := d.formatRow(item.Mode.String(), size, formatTime(item.ModTime, now, d.relativeTimes), name)
+		if item.IsDir {
+			line = dirStyle.Render(line)
+		}
+		if i == d.cursor {
+			line = d.cursorStyle.render(line, d.width)
+		}
+		lines = append(lines, line)
+	}
+
+	for len(lines) < d.height-1 {
+		lines = append(lines, "")
+	}
+	order := "↑"
+	if d.order.reverse {
+		order = "↓"
+	}
+	if !d.order.dirsFirst {
+		order += ", dirs mixed in"
+	}
+	footer := fmt.Sprintf("%d items, sorted by %s %s (s: sort, r: reverse, f: dirs first, b: bytes, t: times)", len(d.items), d.order.by, order)
+	d.scrollbar.draw(lines, 2, d.listHeight(), d.width, d.offset, len(d.items))
+	lines = append(lines, dim.Render(ansi.Truncate(footer, d.width, "…")))
+	return strings.Join(lines, "\n")
+}
+
+// formatRow lays out the columns, cutting the name to the pane
+func (d *DirViewer) formatRow(mode, size, modified, name string) string {
+	sizeWidth := 8
+	if d.exactSizes {
+		sizeWidth = 13 // up to 999,999,999,999
+	}
+	row := fmt.Sprintf("%-10s %*s  %-16s  %s", mode, sizeWidth, size, modified, name)
+	return ansi.Truncate(row, d.width, "…")
+}
+
+// listHeight is the number of rows left for items below the header and
+// column titles, keeping the last line for the footer
+func (d *DirViewer) listHeight() int {
+	return max(1, d.height-3)
+}
+
+func (d *DirViewer) moveCursor(delta int) {
+	d.cursor = max(0, min(d.cursor+delta, len(d.items)-1))
+	d.offset = keepInView(d.cursor, d.offset, d.listHeight(), len(d.items), d.scrollOff)
+}
+
+// sortItems orders the listing by the chosen column, keeping the cursor
+// on the same item
+func (d *DirViewer) sortItems() {
+	var current string
+	if d.cursor < len(d.items) {
+		current = d.items[d.cursor].Name
+	}
+	sort.SliceStable(d.items, func(i, j int) bool {
+		a, b := d.items[i], d.items[j]
+		return d.order.less(
+			sortKey{name: a.Name, dir: a.IsDir, size: a.Size, modTime: a.ModTime},
+			sortKey{name: b.Name, dir: b.IsDir, size: b.Size, modTime: b.ModTime})
+	})
+	for i, item := range d.items {
+		if item.Name == current {
+			d.cursor = i
+		}
+	}
+	d.moveCursor(0)
+}
+
+func (d *DirViewer) SetSize(width, height int) {
+	d.width = width
+	d.height = height
+}
+
+func (d *DirViewer) Focused() bool {
+	return d.focused
+}
+
+func (d *DirViewer) SetFocused(focused bool) {
+	d.focused = focused
+}
+
+// SetScrollOff sets the rows of context kept around the cursor
+func (d *DirViewer) SetScrollOff(lines int) {
+	d.scrollOff = lines
+}
+
+// SetScrollbar sets how the scrollbar is drawn
+func (d *DirViewer) SetScrollbar(style scrollbarStyle) {
+	d.scrollbar = style
+}
+
+// SetCursorStyle sets how the row under the cursor is drawn
+func (d *DirViewer) SetCursorStyle(style cursorStyle) {
+	d.cursorStyle = style
+}
+
+// ClaimsKey keeps "e" from opening a directory in the text editor, and
+// keeps "r" for reversing the sort
+func (d *DirViewer) ClaimsKey(key string) bool {
+	return key == "e" || key == "r"
+}
+
+// FailedLoad returns the directory whose load failed and why, or "" and nil
+// when it did not
+func (d *DirViewer) FailedLoad() (string, error) {
+	if d.err == nil {
+		return "", nil
+	}
+	return d.path, d.err
+}
+
+func (d *DirViewer) Name() string {
+	return "dir"
+}
+
+func (d *DirViewer) CanView(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+func (d *DirViewer) Load(path string) tea.Cmd {
+	d.path = path
+	hide := d.hide
+	return func() tea.Msg {
+		files, err := os.ReadDir(path)
+		if err != nil {
+			return DirListedMsg{Path: path, Err: err}
+		}
+		items := make([]dirItem, 0, len(files))
+		for _, f := range files {
+			if isHidden(f.Name(), hide) {
+				continue
+			}
+			item := dirItem{Name: f.Name(), IsDir: f.IsDir()}
+			if info, err := f.Info(); err == nil {
+				item.Size = info.Size()
+				item.ModTime = info.ModTime()
+				item.Mode = info.Mode()
+			}
+			if f.Type()&os.ModeSymlink != 0 {
+				full := filepath.Join(path, f.Name())
+				item.Link, _ = os.Readlink(full)
+				if info, err := os.Stat(full); err == nil && info.IsDir() {
+					item.IsDir = true
+				}
+			}
+			items = append(items, item)
+		}
+		return DirListedMsg{Path: path, Items: items}
+	}
+}
+
+func (d *DirViewer) centerText(text string) string {
+	style := lipgloss.NewStyle().
+		Width(d.width).
+		Height(d.height).
+		Align(lipgloss.Center, lipgloss.Center)
+	return style.Render(text)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/glamour/styles"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+var update = flag.Bool("update", false, "rewrite the .golden files instead of checking them")
+
+// Golden frames are rendered at a fixed viewer size
+const (
+	goldenWidth  = 60
+	goldenHeight = 16
+)
+
+// goldenExt marks the expected frame stored next to each sample file
+const goldenExt = ".golden"
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	// Plain frames, whatever the terminal running the tests
+	lipgloss.SetColorProfile(termenv.Ascii)
+	glamourStyle = styles.NoTTYStyle
+	os.Exit(m.Run())
+}
+
+// TestGoldenFrames renders every sample file in testdata/golden in the
+// viewer that opens it and compares the frame with the sample's .golden
+// file, or rewrites the golden files with -update
+func TestGoldenFrames(t *testing.T) {
+	dir := filepath.Join("testdata", "golden")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasSuffix(entry.Name(), goldenExt) {
+			continue
+		}
+		sample := filepath.Join(dir, entry.Name())
+		t.Run(entry.Name(), func(t *testing.T) {
+			frame := renderViewer(sample, goldenWidth, goldenHeight) + "\n"
+			if *update {
+				if err := os.WriteFile(sample+goldenExt, []byte(frame), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(sample + goldenExt)
+			if err != nil {
+				t.Fatalf("%v (run with -update)", err)
+			}
+			if frame != string(want) {
+				t.Errorf("frame differs from golden\ngot:\n%s\nwant:\n%s", frame, want)
+			}
+		})
+	}
+}
+
+// renderViewer opens path in a viewer of the given size and returns its
+// frame once loading has finished
+func renderViewer(path string, width, height int) string {
+	r := NewViewerRouter(DefaultConfig())
+	r.SetSize(width, height)
+	r.SetFocused(true)
+	settle(r, r.OpenFile(path, 0))
+	return r.View()
+}
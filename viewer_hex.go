@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// hexViewBytes is how much of a file the hex viewer reads, however large
+// the file is
+const hexViewBytes = 64 << 10
+
+// HexLoadedMsg is sent when the start of a file has been read for the hex
+// viewer
+type HexLoadedMsg struct {
+	Path  string
+	Lines []string // hex dump rows of 16 bytes
+	Size  int64    // size of the whole file
+	Row   int      // row holding the start of the line SeekLine asked for
+	Err   error
+}
+
+// HexViewer shows the start of a file as a hex dump. It is never picked
+// by file type, only by config, so a binary file is shown without reading
+// it as text.
+type HexViewer struct {
+	width   int
+	height  int
+	focused bool
+
+	scrollbar scrollbarStyle
+
+	path   string
+	lines  []string
+	size   int64
+	offset int
+	err    error
+
+	seekLine int // 1-based line of the file the next load scrolls to
+}
+
+func NewHexViewer() *HexViewer {
+	return &HexViewer{scrollbar: defaultScrollbar}
+}
+
+func (h *HexViewer) Init() tea.Cmd {
+	return nil
+}
+
+func (h *HexViewer) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case HexLoadedMsg:
+		if msg.Path == h.path {
+			h.lines, h.size, h.err = msg.Lines, msg.Size, msg.Err
+			h.offset = 0
+			h.scroll(msg.Row - h.height/4)
+		}
+
+	case tea.KeyMsg:
+		if !h.focused {
+			return h, nil
+		}
+		switch msg.String() {
+		case "j", "down":
+			h.scroll(1)
+		case "k", "up":
+			h.scroll(-1)
+		case "d", "ctrl+d":
+			h.scroll(h.height / 2)
+		case "u", "ctrl+u":
+			h.scroll(-h.height / 2)
+		case "g":
+			h.offset = 0
+		case "G":
+			h.scroll(len(h.lines))
+		}
+	}
+	return h, nil
+}
+
+func (h *HexViewer) View() string {
+	if h.path == "" {
+		return h.centerText("Select a file to view")
+	}
+	if h.err != nil {
+		return h.centerText(loadErrorText(h.err))
+	}
+
+	header := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("12")).
+		Render(filepath.Base(h.path))
+	lines := []string{header}
+	end := min(h.offset+h.height-2, len(h.lines))
+	for i := h.offset; i < end; i++ {
+		lines = append(lines, ansi.Truncate(h.lines[i], h.width-2, ""))
+	}
+	for len(lines) < h.height {
+		lines = append(lines, "")
+	}
+	h.scrollbar.draw(lines, 1, h.height-2, h.width, h.offset, len(h.lines))
+
+	note := fmt.Sprintf("hex, %s", humanSize(h.size))
+	if h.size > hexViewBytes {
+		note = fmt.Sprintf("hex, first %s of %s", humanSize(hexViewBytes), humanSize(h.size))
+	}
+	if len(lines) > 1 {
+		lines[len(lines)-1] = lipgloss.NewStyle().Foreground(lipgloss.Color("245")).Render(note)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (h *HexViewer) scroll(delta int) {
+	h.offset = max(0, min(h.offset+delta, len(h.lines)-h.height+2))
+}
+
+func (h *HexViewer) centerText(text string) string {
+	style := lipgloss.NewStyle().
+		Width(h.width).
+		Height(h.height).
+		Align(lipgloss.Center, lipgloss.Center)
+	return style.Render(text)
+}
+
+func (h *HexViewer) SetScrollbar(style scrollbarStyle) {
+	h.scrollbar = style
+}
+
+func (h *HexViewer) SetSize(width, height int) {
+	h.width = width
+	h.height = height
+}
+
+func (h *HexViewer) Focused() bool {
+	return h.focused
+}
+
+func (h *HexViewer) SetFocused(focused bool) {
+	h.focused = focused
+}
+
+// FailedLoad returns the file whose load failed and why, or "" and nil
+// when it did not
+func (h *HexViewer) FailedLoad() (string, error) {
+	if h.err == nil {
+		return "", nil
+	}
+	return h.path, h.err
+}
+
+func (h *HexViewer) Name() string {
+	return "hex"
+}
+
+// CanView is false for every file: the hex viewer is only chosen by config
+func (h *HexViewer) CanView(path string) bool {
+	return false
+}
+
+// SeekLine sets the 1-based line of the file the next load scrolls to,
+// by the row its first byte is in
+func (h *HexViewer) SeekLine(line int) {
+	h.seekLine = line
+}
+
+func (h *HexViewer) Load(path string) tea.Cmd {
+	h.path = path
+	h.lines = nil
+	line := h.seekLine
+	h.seekLine = 0
+	return func() tea.Msg {
+		return hexDump(path, line)
+	}
+}
+
+// hexDump reads up to hexViewBytes of a file as hex dump rows, finding
+// the row a 1-based line starts in among them
+func hexDump(path string, line int) HexLoadedMsg {
+	if err := checkSpecial(path); err != nil {
+		return HexLoadedMsg{Path: path, Err: err}
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return HexLoadedMsg{Path: path, Err: err}
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return HexLoadedMsg{Path: path, Err: err}
+	}
+	data, err := io.ReadAll(io.LimitReader(f, hexViewBytes))
+	if err != nil {
+		return HexLoadedMsg{Path: path, Err: err}
+	}
+	dump := strings.TrimSuffix(hex.Dump(data), "\n")
+	if dump == "" {
+		return HexLoadedMsg{Path: path, Size: info.Size()}
+	}
+	start := 0
+	for range line - 1 {
+		i := bytes.IndexByte(data[start:], '\n')
+		if i < 0 {
+			break
+		}
+		start += i + 1
+	}
+	return HexLoadedMsg{Path: path, Lines: strings.Split(dump, "\n"), Size: info.Size(), Row: start / 16}
+}
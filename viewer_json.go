@@ -3,14 +3,27 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
 )
 
+// defaultJSONMaxDepth is how many levels of a document are built on load
+// unless configured otherwise
+const defaultJSONMaxDepth = 64
+
+// jsonPageSize is how many members of a large object or array are shown
+// at once. Bigger containers are built only when opened, a page at a time.
+const jsonPageSize = 1000
+
 // JSONNode represents a node in the JSON tree
 type JSONNode struct {
 	Key      string
@@ -19,6 +32,14 @@ type JSONNode struct {
 	Expanded bool
 	Depth    int
 	IsArray  bool
+	Parent   *JSONNode
+	Deferred bool // container below the depth limit, children not built yet
+	Page     int  // page of members shown, for containers over jsonPageSize
+}
+
+// isContainer reports whether node has children to show, built or not
+func (node *JSONNode) isContainer() bool {
+	return len(node.Children) > 0 || node.Deferred
 }
 
 // JSONViewer displays JSON files as a collapsible tree
@@ -27,15 +48,45 @@ type JSONViewer struct {
 	height  int
 	focused bool
 
+	numbers     NumberFormat
+	maxDepth    int // levels of nodes built at a time
+	scrollOff   int
+	cursorStyle cursorStyle
+	scrollbar   scrollbarStyle
+	wrap        bool     // j and k wrap around at the ends
+	caseMode    caseMode // how searches treat letter case
+
 	path   string
 	root   *JSONNode
+	total  int // nodes in the document, counted on load
 	cursor int
 	offset int
 	err    error
+
+	lines      bool           // JSON Lines: one value per line, saved that way
+	focusStack []*JSONNode    // zoomed subtrees, innermost last
+	restore    *jsonViewState // view to reapply when a reload arrives
+	modTime    time.Time      // of the file when it was read
+
+	savedViews     map[string]savedJSONView // views from earlier sessions, by file
+	savedViewsPath string
+
+	query     string    // last search, found again with n and N
+	matches   [][]int   // member indices down to each match, nil when stale
+	matchRoot *JSONNode // node the matches were found under
+	match     int       // index of the match last moved to, or -1
+
+	showSpans bool                // footer shows the cursor node's byte offsets
+	spans     map[string]jsonSpan // by node path, nil until read
+
+	dirty  bool   // scalar values edited since load or save
+	status string // result of the last edit or save
+
+	seekLine int // 1-based line of the file the next load puts the cursor on
 }
 
-func NewJSONViewer() *JSONViewer {
-	return &JSONViewer{}
+func NewJSONViewer(cfg JSONConfig) *JSONViewer {
+	return &JSONViewer{numbers: cfg.Numbers, maxDepth: cfg.MaxDepth, cursorStyle: defaultCursor, scrollbar: defaultScrollbar}
 }
 
 func (j *JSONViewer) Init() tea.Cmd {
@@ -47,31 +98,110 @@ func (j *JSONViewer) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case JSONLoadedMsg:
 		if msg.Path == j.path {
 			j.root = msg.Root
+			j.total = msg.Total
+			j.lines = msg.Lines
+			j.modTime = msg.ModTime
 			j.cursor = 0
 			j.offset = 0
 			j.err = msg.Err
+			j.focusStack = nil
+			if j.restore != nil && j.root != nil {
+				j.applyState(j.restore)
+			}
+			j.restore = nil
+			if msg.Seek != nil && j.root != nil {
+				j.focusStack = nil
+				j.cursor = j.indexOf(j.reveal(j.root, msg.Seek))
+				j.ensureVisible()
+			}
+			j.matches = nil
+			j.match = -1
+			j.dirty = false
+			j.status = ""
+			if j.showSpans && j.root != nil {
+				return j, j.loadSpans()
+			}
 		}
 
+	case JSONSpansMsg:
+		if msg.Path == j.path && j.showSpans {
+			j.spans = msg.Spans
+			if msg.Err != nil {
+				j.status = "No byte offsets: " + msg.Err.Error()
+			}
+		}
+
+	case JSONSavedMsg:
+		if msg.Path == j.path {
+			if msg.Err != nil {
+				j.status = "Save failed: " + msg.Err.Error()
+			} else {
+				j.dirty = false
+				j.status = "Saved"
+			}
+		}
+
+	case ClipboardMsg:
+		j.status = msg.Status()
+
 	case tea.KeyMsg:
 		if !j.focused {
 			return j, nil
 		}
+		j.status = ""
 		visible := j.visibleNodes()
 		switch msg.String() {
-		case "j", "down":
-			if j.cursor < len(visible)-1 {
-				j.cursor++
-				j.ensureVisible()
+		case "e":
+			if j.cursor < len(visible) && isScalar(visible[j.cursor]) {
+				return j, j.startEdit(visible[j.cursor])
 			}
-		case "k", "up":
-			if j.cursor > 0 {
-				j.cursor--
-				j.ensureVisible()
+		case "Y":
+			return j, yankFile(j.path)
+		case "/":
+			return j, j.startSearch()
+		case "b":
+			return j, j.toggleSpans()
+		case "n":
+			j.nextMatch(1)
+		case "N":
+			j.nextMatch(-1)
+		case "ctrl+s":
+			if j.dirty {
+				return j, j.save()
 			}
+		case "z":
+			if j.cursor < len(visible) && visible[j.cursor].isContainer() {
+				j.buildDeferred(visible[j.cursor])
+				j.pushFocus(visible[j.cursor])
+			}
+		case "Z":
+			j.popFocus()
+		case "]", "[":
+			delta := 1
+			if msg.String() == "[" {
+				delta = -1
+			}
+			if j.cursor < len(visible) {
+				j.turnPage(visible[j.cursor], delta)
+			}
+		case "c":
+			if j.cursor < len(visible) {
+				j.collapseSiblings(visible[j.cursor])
+			}
+		case "%":
+			j.cursor = matchingRow(visible, j.cursor)
+			j.ensureVisible()
+		case "j", "down":
+			j.cursor = stepCursor(j.cursor, 1, len(visible), j.wrap)
+			j.ensureVisible()
+		case "k", "up":
+			j.cursor = stepCursor(j.cursor, -1, len(visible), j.wrap)
+			j.ensureVisible()
 		case "enter", "l", "right":
 			if j.cursor < len(visible) {
 				node := visible[j.cursor]
-				if len(node.Children) > 0 {
+				if node.isContainer() {
+					j.buildDeferred(node)
 					node.Expanded = !node.Expanded
 				}
 			}
@@ -79,7 +209,7 @@ func (j *JSONViewer) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			// Collapse current node or go to parent
 			if j.cursor < len(visible) {
 				node := visible[j.cursor]
-				if node.Expanded && len(node.Children) > 0 {
+				if node.Expanded && node.isContainer() {
 					node.Expanded = false
 				}
 			}
@@ -112,7 +242,7 @@ func (j *JSONViewer) View() string {
 		return j.centerText("Select a JSON file to view")
 	}
 	if j.err != nil {
-		return j.centerText("Error: " + j.err.Error())
+		return j.centerText(loadErrorText(j.err))
 	}
 	if j.root == nil {
 		return j.centerText("Loading...")
@@ -121,11 +251,21 @@ func (j *JSONViewer) View() string {
 	visible := j.visibleNodes()
 	viewHeight := j.height - 2 // -1 for header, -1 for padding
 
-	// Header with filename
+	// Header with filename, focused subtree and modified indicator
+	name := filepath.Base(j.path)
+	for _, node := range j.focusStack {
+		name += " › " + node.Key
+	}
+	if j.dirty {
+		name += " [+]"
+	}
+	name += compressedNote(j.path)
 	header := lipgloss.NewStyle().
 		Bold(true).
 		Foreground(lipgloss.Color("12")).
-		Render(filepath.Base(j.path))
+		Render(name)
+	header += lipgloss.NewStyle().Foreground(lipgloss.Color("245")).Render("  " + nodeCounts(visible, j.total) + j.matchCount())
+	header = ansi.Truncate(header, j.width, "…")
 
 	var lines []string
 	lines = append(lines, header)
@@ -140,15 +280,17 @@ func (j *JSONViewer) View() string {
 	numberStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("178"))
 	boolStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("168"))
 	nullStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
-	cursorStyle := lipgloss.NewStyle().Background(lipgloss.Color("237"))
 
+	baseDepth := j.viewRoot().Depth
+	var cursorNode *JSONNode
+	cursorWidth := 0
 	for i := j.offset; i < end; i++ {
 		node := visible[i]
-		indent := strings.Repeat("  ", node.Depth)
+		indent := strings.Repeat("  ", node.Depth-baseDepth)
 
 		var line string
 		prefix := " "
-		if len(node.Children) > 0 {
+		if node.isContainer() {
 			if node.Expanded {
 				prefix = "▼"
 			} else {
@@ -165,39 +307,187 @@ func (j *JSONViewer) View() string {
 
 		line = fmt.Sprintf("%s%s %s%s", indent, prefix, keyPart, valuePart)
 
-		// Truncate long lines
-		if len(line) > j.width-2 {
-			line = line[:j.width-5] + "..."
+		if i == j.cursor {
+			cursorNode = node
+			cursorWidth = ansi.StringWidth(line)
 		}
+		line = clipLine(line, j.width-2)
 
 		if i == j.cursor {
-			line = cursorStyle.Render(line)
+			line = j.cursorStyle.render(line, j.width)
 		}
 
 		lines = append(lines, line)
 	}
 
-	// Pad to full height
-	for len(lines) < j.height {
+	// Pad to full height, keeping the last line for the prompt or status
+	for len(lines) < j.height-1 {
 		lines = append(lines, "")
 	}
+	j.scrollbar.draw(lines, 1, viewHeight, j.width, j.offset, len(visible))
+	lines = append(lines, j.footer(cursorNode, cursorWidth))
 
 	return strings.Join(lines, "\n")
 }
 
-func (j *JSONViewer) renderValue(node *JSONNode, stringStyle, numberStyle, boolStyle, nullStyle lipgloss.Style) string {
-	if len(node.Children) > 0 {
-		count := len(node.Children)
-		if node.IsArray {
-			if node.Expanded {
-				return fmt.Sprintf("[%d items]", count)
-			}
-			return fmt.Sprintf("[%d items...]", count)
+// footer shows the status or save hint, then the cursor node's byte
+// offsets when b turned them on, otherwise the full width of the cursor
+// line when it is cut off
+func (j *JSONViewer) footer(cursor *JSONNode, cursorWidth int) string {
+	style := lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
+	if j.status != "" {
+		return style.Render(j.status)
+	}
+	if j.dirty {
+		return style.Render("Ctrl+S: save changes")
+	}
+	if j.showSpans && cursor != nil {
+		return style.Render(j.spanText(cursor))
+	}
+	if cursorWidth > j.width-2 {
+		return overflowMarker + style.Render(fmt.Sprintf(" line is %d columns", cursorWidth))
+	}
+	return ""
+}
+
+// Modified reports whether values have been edited since load or save
+func (j *JSONViewer) Modified() bool {
+	return j.dirty
+}
+
+// ClaimsKey takes "e" on scalar leaves for inline editing, leaving it to
+// open the text editor everywhere else
+func (j *JSONViewer) ClaimsKey(key string) bool {
+	if key != "e" {
+		return false
+	}
+	visible := j.visibleNodes()
+	return j.cursor < len(visible) && isScalar(visible[j.cursor])
+}
+
+// isScalar reports whether a node holds an editable string, number, bool
+// or null
+func isScalar(node *JSONNode) bool {
+	switch node.Value.(type) {
+	case string, json.Number, bool, nil:
+		return true
+	}
+	return false
+}
+
+// startEdit prompts for a new value for a scalar leaf
+func (j *JSONViewer) startEdit(node *JSONNode) tea.Cmd {
+	j.status = ""
+	if compressionExt(j.path) != "" {
+		j.status = "Compressed files are read-only"
+		return nil
+	}
+	current := ""
+	if str, ok := node.Value.(string); ok {
+		current = str
+	} else {
+		raw, _ := json.Marshal(node.Value)
+		current = string(raw)
+	}
+	root := j.root
+	return askInput("Value: ", current, func(typed string) tea.Cmd {
+		if j.root != root {
+			return nil // the file was reloaded under the prompt
 		}
-		if node.Expanded {
-			return fmt.Sprintf("{%d keys}", count)
+		value, err := parseScalar(node.Value, typed)
+		if err != nil {
+			j.status = "Invalid value: " + err.Error()
+			return nil
+		}
+		node.Value = value
+		j.matches = nil
+		j.dirty = true
+		return nil
+	})
+}
+
+// parseScalar converts prompt input to a value of the same type as old.
+// Strings are taken verbatim; null accepts any JSON scalar.
+func parseScalar(old any, input string) (any, error) {
+	switch old.(type) {
+	case string:
+		return input, nil
+	case json.Number:
+		// Validate as a JSON number but keep the token as typed
+		v, err := decodeJSON([]byte(input))
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := v.(json.Number); !ok {
+			return nil, fmt.Errorf("not a number: %s", input)
+		}
+		return v, nil
+	case bool:
+		return strconv.ParseBool(strings.TrimSpace(input))
+	}
+	v, err := decodeJSON([]byte(input))
+	if err != nil {
+		return nil, err
+	}
+	switch v.(type) {
+	case *jsonObject, []any:
+		return nil, fmt.Errorf("only scalar values can be edited")
+	}
+	return v, nil
+}
+
+// save re-serializes the tree and writes it back to the file
+func (j *JSONViewer) save() tea.Cmd {
+	path := j.path
+	var data []byte
+	var err error
+	if values, ok := nodeValue(j.root).([]any); ok && j.lines {
+		data, err = marshalLines(values)
+	} else {
+		data, err = encodeJSON(nodeValue(j.root), "  ")
+	}
+	if err != nil {
+		return func() tea.Msg {
+			return JSONSavedMsg{Path: path, Err: err}
+		}
+	}
+	return func() tea.Msg {
+		err := writeFileAtomic(path, data)
+		return JSONSavedMsg{Path: path, Err: err}
+	}
+}
+
+// nodeValue rebuilds a plain value from the tree, so leaf edits are picked
+// up rather than the containers' original decoded values
+func nodeValue(node *JSONNode) any {
+	if node.Deferred {
+		return node.Value // never built, so never edited
+	}
+	if containerLen(node.Value) > jsonPageSize {
+		// Only one page is built; the rest are still the decoded values
+		foldPage(node)
+		return node.Value
+	}
+	switch node.Value.(type) {
+	case *jsonObject:
+		obj := &jsonObject{values: make(map[string]any, len(node.Children))}
+		for _, child := range node.Children {
+			obj.set(child.Key, nodeValue(child))
+		}
+		return obj
+	case []any:
+		arr := make([]any, 0, len(node.Children))
+		for _, child := range node.Children {
+			arr = append(arr, nodeValue(child))
 		}
-		return fmt.Sprintf("{%d keys...}", count)
+		return arr
+	}
+	return node.Value
+}
+
+func (j *JSONViewer) renderValue(node *JSONNode, stringStyle, numberStyle, boolStyle, nullStyle lipgloss.Style) string {
+	if count := containerLen(node.Value); count > 0 {
+		return containerLabel(node, count)
 	}
 
 	switch v := node.Value.(type) {
@@ -207,46 +497,222 @@ func (j *JSONViewer) renderValue(node *JSONNode, stringStyle, numberStyle, boolS
 			s = s[:47] + "...\""
 		}
 		return stringStyle.Render(s)
-	case float64:
-		if v == float64(int(v)) {
-			return numberStyle.Render(fmt.Sprintf("%d", int(v)))
-		}
-		return numberStyle.Render(fmt.Sprintf("%g", v))
+	case json.Number:
+		return numberStyle.Render(formatNumber(v, j.numbers))
 	case bool:
 		return boolStyle.Render(fmt.Sprintf("%t", v))
 	case nil:
 		return nullStyle.Render("null")
+	case *jsonLine:
+		if v.err != nil {
+			text := fmt.Sprintf("line %d: %v", v.num, v.err)
+			return lipgloss.NewStyle().Foreground(lipgloss.Color("9")).Render(text)
+		}
+		return v.text
 	default:
 		return fmt.Sprintf("%v", v)
 	}
 }
 
+// containerLabel summarizes an object or array: its size, whether it is
+// open, and which page of a large one is showing
+func containerLabel(node *JSONNode, count int) string {
+	label := fmt.Sprintf("{%d keys", count)
+	end := "}"
+	if node.IsArray {
+		label = fmt.Sprintf("[%d items", count)
+		end = "]"
+	}
+	switch {
+	case node.Deferred && count <= jsonPageSize:
+		return label + end + " …(deeper)"
+	case !node.Expanded:
+		return label + "..." + end
+	case count > jsonPageSize:
+		lo, hi := pageBounds(node)
+		return fmt.Sprintf("%s%s showing %d–%d of %d", label, end, lo+1, hi, count)
+	}
+	return label + end
+}
+
+// formatNumber renders a number token in the configured style. Integer
+// tokens are always shown exactly, however large.
+func formatNumber(n json.Number, f NumberFormat) string {
+	if f.Style == "raw" {
+		return n.String()
+	}
+	var s string
+	if !strings.ContainsAny(n.String(), ".eE") {
+		s = n.String()
+	} else if v, err := n.Float64(); err == nil {
+		switch abs := math.Abs(v); {
+		case f.Style == "fixed":
+			s = strconv.FormatFloat(v, 'f', f.Precision, 64)
+		case abs == 0 || (abs >= 1e-4 && abs < 1e21):
+			// Shortest exact digits, without switching to an exponent
+			// for ordinary magnitudes the way %g does
+			s = strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			s = strconv.FormatFloat(v, 'g', -1, 64)
+		}
+	} else {
+		return n.String()
+	}
+	if f.Thousands {
+		s = groupThousands(s)
+	}
+	return s
+}
+
+// groupThousands inserts commas into the integer part of a formatted
+// number, leaving exponent forms alone
+func groupThousands(s string) string {
+	if strings.ContainsAny(s, "eE") {
+		return s
+	}
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	intPart, frac, hasFrac := strings.Cut(s, ".")
+	var b strings.Builder
+	for i, c := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(c)
+	}
+	if hasFrac {
+		b.WriteString("." + frac)
+	}
+	return sign + b.String()
+}
+
 func (j *JSONViewer) visibleNodes() []*JSONNode {
 	if j.root == nil {
 		return nil
 	}
 	var nodes []*JSONNode
-	j.collectVisible(j.root, &nodes)
+	walkNodes(j.viewRoot(), func(node *JSONNode) bool {
+		nodes = append(nodes, node)
+		return node.Expanded
+	})
 	return nodes
 }
 
-func (j *JSONViewer) collectVisible(node *JSONNode, nodes *[]*JSONNode) {
-	*nodes = append(*nodes, node)
-	if node.Expanded {
-		for _, child := range node.Children {
-			j.collectVisible(child, nodes)
+// viewRoot is the node rendered as the top of the tree: the innermost
+// focused subtree, or the document root
+func (j *JSONViewer) viewRoot() *JSONNode {
+	if len(j.focusStack) > 0 {
+		return j.focusStack[len(j.focusStack)-1]
+	}
+	return j.root
+}
+
+// pushFocus zooms into a subtree, rendering it as the root
+func (j *JSONViewer) pushFocus(node *JSONNode) {
+	j.focusStack = append(j.focusStack, node)
+	node.Expanded = true
+	j.cursor = 0
+	j.offset = 0
+}
+
+// popFocus unwinds one level of focus, leaving the cursor on the subtree
+// that was zoomed into
+func (j *JSONViewer) popFocus() {
+	if len(j.focusStack) == 0 {
+		return
+	}
+	node := j.focusStack[len(j.focusStack)-1]
+	j.focusStack = j.focusStack[:len(j.focusStack)-1]
+	j.cursor = j.indexOf(node)
+	j.ensureVisible()
+}
+
+// collapseSiblings collapses every container off the path from the view
+// root to node, leaving only that path open
+func (j *JSONViewer) collapseSiblings(node *JSONNode) {
+	top := j.viewRoot()
+	for n := node; n != top && n.Parent != nil; n = n.Parent {
+		for _, sibling := range n.Parent.Children {
+			if sibling != n {
+				sibling.Expanded = false
+			}
 		}
 	}
+	j.cursor = j.indexOf(node)
+	j.ensureVisible()
 }
 
-func (j *JSONViewer) ensureVisible() {
-	viewHeight := j.height - 2
-	if j.cursor < j.offset {
-		j.offset = j.cursor
+// matchingRow is where % goes from row: past the subtree of an open
+// container, back to the container whose subtree ends just above, or up
+// to the container holding row
+func matchingRow(visible []*JSONNode, row int) int {
+	if row >= len(visible) {
+		return row
+	}
+	node := visible[row]
+	if node.Expanded && len(node.Children) > 0 {
+		end := row + 1
+		for end < len(visible) && visible[end].Depth > node.Depth {
+			end++
+		}
+		return min(end, len(visible)-1)
+	}
+	if row > 0 {
+		above := visible[row-1]
+		for above.Depth > node.Depth {
+			above = above.Parent
+		}
+		if above != visible[row-1] && above.Depth == node.Depth {
+			return slices.Index(visible, above)
+		}
+	}
+	for i := row - 1; i >= 0; i-- {
+		if visible[i] == node.Parent {
+			return i
+		}
 	}
-	if j.cursor >= j.offset+viewHeight {
-		j.offset = j.cursor - viewHeight + 1
+	return row
+}
+
+// indexOf returns the position of node among the visible nodes, or 0
+func (j *JSONViewer) indexOf(node *JSONNode) int {
+	for i, n := range j.visibleNodes() {
+		if n == node {
+			return i
+		}
 	}
+	return 0
+}
+
+func (j *JSONViewer) ensureVisible() {
+	j.offset = keepInView(j.cursor, j.offset, j.height-2, len(j.visibleNodes()), j.scrollOff)
+}
+
+// SetScrollOff sets the rows of context kept above and below the cursor
+func (j *JSONViewer) SetScrollOff(lines int) {
+	j.scrollOff = lines
+}
+
+// SetCaseMode sets how searches treat letter case
+func (j *JSONViewer) SetCaseMode(mode caseMode) {
+	j.caseMode = mode
+}
+
+// SetWrapAround sets whether j and k wrap around at the ends of the tree
+func (j *JSONViewer) SetWrapAround(wrap bool) {
+	j.wrap = wrap
+}
+
+// SetScrollbar sets how the scrollbar is drawn
+func (j *JSONViewer) SetScrollbar(style scrollbarStyle) {
+	j.scrollbar = style
+}
+
+// SetCursorStyle sets how the row under the cursor is drawn
+func (j *JSONViewer) SetCursorStyle(style cursorStyle) {
+	j.cursorStyle = style
 }
 
 func (j *JSONViewer) SetSize(width, height int) {
@@ -262,56 +728,353 @@ func (j *JSONViewer) SetFocused(focused bool) {
 	j.focused = focused
 }
 
+// FailedLoad returns the file whose load failed and why, or "" and nil
+// when it did not
+func (j *JSONViewer) FailedLoad() (string, error) {
+	if j.err == nil {
+		return "", nil
+	}
+	return j.path, j.err
+}
+
+// SeekLine sets the 1-based line of the file whose value the cursor is
+// put on when the next file loads
+func (j *JSONViewer) SeekLine(line int) {
+	j.seekLine = line
+}
+
+func (j *JSONViewer) Name() string {
+	return "json"
+}
+
 func (j *JSONViewer) CanView(path string) bool {
 	ext := strings.ToLower(filepath.Ext(path))
-	return ext == ".json"
+	return ext == ".json" || isJSONLines(path)
 }
 
 func (j *JSONViewer) Load(path string) tea.Cmd {
+	// Reloading the same file keeps the view; a different file starts fresh
+	j.restore = nil
+	if path == j.path && j.root != nil {
+		j.restore = j.captureState()
+	} else {
+		if j.root != nil {
+			j.saveViews()
+		}
+		j.restore = j.savedView(path)
+		j.query = ""
+	}
 	j.path = path
+	maxDepth, seekLine := j.maxDepth, j.seekLine
+	j.seekLine = 0
 	return func() tea.Msg {
-		content, err := os.ReadFile(path)
+		info, err := os.Stat(path)
+		if err != nil {
+			return JSONLoadedMsg{Path: path, Err: err}
+		}
+		if err := checkSpecial(path); err != nil {
+			return JSONLoadedMsg{Path: path, Err: err}
+		}
+		content, err := readContent(path)
 		if err != nil {
 			return JSONLoadedMsg{Path: path, Err: err}
 		}
 
+		// JSON Lines files, and .json files that turn out to be one value
+		// per line, are shown as an array of their lines
 		var data any
-		if err := json.Unmarshal(content, &data); err != nil {
-			return JSONLoadedMsg{Path: path, Err: err}
+		lines := isJSONLines(innerPath(path))
+		if !lines {
+			data, err = decodeJSON(content)
+			if err != nil && !looksLikeJSONLines(content) {
+				return JSONLoadedMsg{Path: path, Err: err}
+			}
+			lines = err != nil
+		}
+		var seek []int
+		if lines {
+			data = splitJSONLines(content)
+			if seekLine > 0 {
+				seek = seekMember(data.([]any), seekLine)
+			}
+		} else if seekLine > 0 {
+			seek = seekPath(content, data, seekLine)
 		}
 
-		root := buildTree("", data, 0)
+		root := buildTree("", data, maxDepth)
 		// Auto-expand root level
 		root.Expanded = true
 
-		return JSONLoadedMsg{Path: path, Root: root}
+		// Counted after building, which parses the first page of lines
+		return JSONLoadedMsg{Path: path, Root: root, Total: countValues(data), Lines: lines, ModTime: info.ModTime(), Seek: seek}
 	}
 }
 
-func buildTree(key string, value any, depth int) *JSONNode {
-	node := &JSONNode{
-		Key:   key,
-		Value: value,
-		Depth: depth,
+// jsonViewState records expansion, focus and cursor by node path, so it
+// can be reapplied to a tree rebuilt from a reloaded file
+type jsonViewState struct {
+	expanded map[string]bool
+	pages    map[string]int
+	focus    []string
+	cursor   string
+}
+
+// nodePathSep joins keys in node paths. Keys are not checked for it, so a
+// key written with \u0000 can give two different nodes the same path.
+const nodePathSep = "\x00"
+
+// nodePath returns the keys from the root down to node
+func nodePath(node *JSONNode) string {
+	var keys []string
+	for n := node; n.Parent != nil; n = n.Parent {
+		keys = append(keys, n.Key)
+	}
+	for i, k := 0, len(keys)-1; i < k; i, k = i+1, k-1 {
+		keys[i], keys[k] = keys[k], keys[i]
 	}
+	return strings.Join(keys, nodePathSep)
+}
 
+// findNode follows a node path from root, returning the deepest node that
+// still exists along it
+func findNode(root *JSONNode, path string) *JSONNode {
+	node := root
+	if path == "" {
+		return node
+	}
+	for _, key := range strings.Split(path, nodePathSep) {
+		var next *JSONNode
+		for _, child := range node.Children {
+			if child.Key == key {
+				next = child
+				break
+			}
+		}
+		if next == nil {
+			break
+		}
+		node = next
+	}
+	return node
+}
+
+func (j *JSONViewer) captureState() *jsonViewState {
+	state := &jsonViewState{expanded: make(map[string]bool), pages: make(map[string]int)}
+	walkNodes(j.root, func(node *JSONNode) bool {
+		if node.Expanded {
+			state.expanded[nodePath(node)] = true
+		}
+		if node.Page > 0 {
+			state.pages[nodePath(node)] = node.Page
+		}
+		return true
+	})
+	for _, node := range j.focusStack {
+		state.focus = append(state.focus, nodePath(node))
+	}
+	if visible := j.visibleNodes(); j.cursor < len(visible) {
+		state.cursor = nodePath(visible[j.cursor])
+	}
+	return state
+}
+
+// applyState reapplies a captured view to the current tree. Paths that no
+// longer exist are dropped; the cursor falls back to its nearest surviving
+// ancestor.
+func (j *JSONViewer) applyState(state *jsonViewState) {
+	walkNodes(j.root, func(node *JSONNode) bool {
+		if page := state.pages[nodePath(node)]; page > 0 {
+			j.setPage(node, page)
+		}
+		node.Expanded = state.expanded[nodePath(node)]
+		if node.Expanded {
+			j.buildDeferred(node) // so expanded paths below it are found
+		}
+		return true
+	})
+
+	for _, path := range state.focus {
+		node := findNode(j.root, path)
+		if nodePath(node) != path || !node.isContainer() {
+			break
+		}
+		j.focusStack = append(j.focusStack, node)
+	}
+	j.cursor = j.indexOf(findNode(j.root, state.cursor))
+	j.ensureVisible()
+}
+
+// countValues counts a decoded value and every value inside it
+func countValues(value any) int {
+	count := 0
+	stack := []any{value}
+	for len(stack) > 0 {
+		v := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		count++
+		switch v := v.(type) {
+		case *jsonObject:
+			for _, child := range v.values {
+				stack = append(stack, child)
+			}
+		case []any:
+			stack = append(stack, v...)
+		}
+	}
+	return count
+}
+
+// walkNodes visits root and the nodes under it in display order,
+// descending into a node's children only when visit returns true. It
+// keeps its own stack, so deep documents cannot exhaust the goroutine's.
+func walkNodes(root *JSONNode, visit func(*JSONNode) bool) {
+	stack := []*JSONNode{root}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if !visit(node) {
+			continue
+		}
+		for i := len(node.Children) - 1; i >= 0; i-- {
+			stack = append(stack, node.Children[i])
+		}
+	}
+}
+
+// nodeCounts summarizes how much of the document is open: rows shown of
+// all nodes, and how many of the shown containers are expanded
+func nodeCounts(visible []*JSONNode, total int) string {
+	expanded := 0
+	for _, node := range visible {
+		if node.Expanded && node.isContainer() {
+			expanded++
+		}
+	}
+	return fmt.Sprintf("%d/%d nodes, %d expanded", len(visible), total, expanded)
+}
+
+// buildTree makes the root node of a decoded document, with nodes built
+// maxDepth levels down
+func buildTree(key string, value any, maxDepth int) *JSONNode {
+	root := &JSONNode{Key: key, Value: value}
+	buildChildren(root, maxDepth)
+	return root
+}
+
+// buildChildren builds the nodes under top, down to maxDepth levels below
+// it. Containers at the limit, and those over jsonPageSize, are left
+// deferred, to be built when opened, and an explicit stack replaces
+// recursion, so however deep a document nests it never exhausts the
+// goroutine stack. It parses JSON Lines members as it builds them and
+// returns how many values that added to the document.
+func buildChildren(top *JSONNode, maxDepth int) int {
+	added := 0
+	stack := []*JSONNode{top}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		_, node.IsArray = node.Value.([]any)
+		count := containerLen(node.Value)
+		tooDeep := node.Depth-top.Depth >= maxDepth
+		node.Deferred = count > 0 && (tooDeep || node != top && count > jsonPageSize)
+		if node.Deferred {
+			continue
+		}
+		lo, hi := pageBounds(node)
+		switch v := node.Value.(type) {
+		case *jsonObject:
+			for _, k := range v.keys[lo:hi] {
+				node.Children = append(node.Children, &JSONNode{Key: k, Value: v.values[k], Depth: node.Depth + 1, Parent: node})
+			}
+		case []any:
+			added += parseLines(v[lo:hi])
+			for i := lo; i < hi; i++ {
+				node.Children = append(node.Children, &JSONNode{Key: fmt.Sprintf("[%d]", i), Value: v[i], Depth: node.Depth + 1, Parent: node})
+			}
+		}
+		stack = append(stack, node.Children...)
+	}
+	return added
+}
+
+// containerLen is the number of members of an object or array value
+func containerLen(value any) int {
 	switch v := value.(type) {
-	case map[string]any:
-		node.Expanded = false
-		for k, val := range v {
-			child := buildTree(k, val, depth+1)
-			node.Children = append(node.Children, child)
+	case *jsonObject:
+		return len(v.keys)
+	case []any:
+		return len(v)
+	}
+	return 0
+}
+
+// pageBounds returns the range of members a container shows: all of them,
+// or its current page when there are more than jsonPageSize
+func pageBounds(node *JSONNode) (int, int) {
+	count := containerLen(node.Value)
+	if count <= jsonPageSize {
+		return 0, count
+	}
+	lo := node.Page * jsonPageSize
+	return lo, min(lo+jsonPageSize, count)
+}
+
+// foldPage writes the values of a paged container's built children back
+// into its decoded value, so edits survive the page being replaced
+func foldPage(node *JSONNode) {
+	lo, _ := pageBounds(node)
+	switch v := node.Value.(type) {
+	case *jsonObject:
+		for _, child := range node.Children {
+			v.set(child.Key, nodeValue(child))
 		}
 	case []any:
-		node.IsArray = true
-		node.Expanded = false
-		for i, val := range v {
-			child := buildTree(fmt.Sprintf("[%d]", i), val, depth+1)
-			node.Children = append(node.Children, child)
+		for i, child := range node.Children {
+			v[lo+i] = nodeValue(child)
 		}
 	}
+}
 
-	return node
+// buildDeferred builds a deferred node's children, another depth limit
+// deep, before it is opened
+func (j *JSONViewer) buildDeferred(node *JSONNode) {
+	if node.Deferred {
+		buildChildren(node, j.maxDepth)
+	}
+}
+
+// setPage shows another page of a large container, rebuilding its
+// children if they were built
+func (j *JSONViewer) setPage(node *JSONNode, page int) {
+	count := containerLen(node.Value)
+	if count <= jsonPageSize {
+		return
+	}
+	page = max(0, min(page, (count-1)/jsonPageSize))
+	if node.Deferred {
+		node.Page = page
+		return
+	}
+	foldPage(node)
+	node.Children = nil
+	node.Page = page
+	j.total += buildChildren(node, j.maxDepth)
+}
+
+// turnPage moves the paged container holding node, or node itself, on by
+// delta pages and leaves the cursor on it
+func (j *JSONViewer) turnPage(node *JSONNode, delta int) {
+	top := j.viewRoot()
+	for containerLen(node.Value) <= jsonPageSize || !node.Expanded {
+		if node == top || node.Parent == nil {
+			return
+		}
+		node = node.Parent
+	}
+	j.setPage(node, node.Page+delta)
+	j.cursor = j.indexOf(node)
+	j.ensureVisible()
 }
 
 func (j *JSONViewer) centerText(text string) string {
@@ -324,7 +1087,17 @@ func (j *JSONViewer) centerText(text string) string {
 
 // JSONLoadedMsg is sent when JSON has been parsed
 type JSONLoadedMsg struct {
+	Path    string
+	Root    *JSONNode
+	Total   int       // nodes in the document
+	Lines   bool      // read as JSON Lines
+	ModTime time.Time // of the file as read
+	Seek    []int     // member indices down to the node SeekLine asked for, nil for none
+	Err     error
+}
+
+// JSONSavedMsg is sent when an edited JSON document has been written
+type JSONSavedMsg struct {
 	Path string
-	Root *JSONNode
 	Err  error
 }
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+)
+
+// jsonLine is a line of a JSON Lines file that is not parsed yet, or, with
+// err set, one that did not parse. Lines are parsed a page at a time, as
+// the root's pages are built.
+type jsonLine struct {
+	text string
+	num  int // 1-based line in the file
+	err  error
+}
+
+// MarshalJSON gives the line as written, or as a string when it is not
+// valid JSON
+func (l *jsonLine) MarshalJSON() ([]byte, error) {
+	if l.err != nil || !json.Valid([]byte(l.text)) {
+		return json.Marshal(l.text)
+	}
+	return []byte(l.text), nil
+}
+
+// isJSONLines reports whether the extension says a file holds one JSON
+// value per line
+func isJSONLines(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ndjson", ".jsonl":
+		return true
+	}
+	return false
+}
+
+// looksLikeJSONLines reports whether content that failed to parse as one
+// document is JSON Lines instead: more than one line, the first of which
+// is a value on its own
+func looksLikeJSONLines(content []byte) bool {
+	first, rest, found := bytes.Cut(bytes.TrimSpace(content), []byte("\n"))
+	if !found || len(bytes.TrimSpace(rest)) == 0 {
+		return false
+	}
+	_, err := decodeJSON(first)
+	return err == nil
+}
+
+// splitJSONLines makes the array a JSON Lines file is shown as, one
+// unparsed member per non-blank line
+func splitJSONLines(content []byte) []any {
+	var lines []any
+	for i, text := range strings.Split(string(content), "\n") {
+		text = strings.TrimSuffix(text, "\r")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		lines = append(lines, &jsonLine{text: text, num: i + 1})
+	}
+	if lines == nil {
+		lines = []any{}
+	}
+	return lines
+}
+
+// parseLines decodes the unparsed lines among values in place, leaving
+// those that fail as they are with their error, and returns how many
+// values the decoded lines added to the document
+func parseLines(values []any) int {
+	added := 0
+	for i, v := range values {
+		line, ok := v.(*jsonLine)
+		if !ok || line.err != nil {
+			continue
+		}
+		value, err := decodeJSON([]byte(line.text))
+		if err != nil {
+			line.err = err
+			continue
+		}
+		values[i] = value
+		added += countValues(value) - 1
+	}
+	return added
+}
+
+// marshalLines writes a JSON Lines document back one member per line.
+// Lines never parsed, and those that failed to, are written as read.
+func marshalLines(values []any) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, v := range values {
+		if line, ok := v.(*jsonLine); ok {
+			buf.WriteString(line.text + "\n")
+			continue
+		}
+		data, err := encodeJSON(v, "") // ends in a newline
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(data)
+	}
+	return buf.Bytes(), nil
+}
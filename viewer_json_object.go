@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+)
+
+// jsonObject is a decoded object that keeps its keys in the order the
+// document has them, so the tree shows them that way and saving writes
+// them back as they were
+type jsonObject struct {
+	keys   []string
+	values map[string]any
+}
+
+// set adds or replaces a member; a repeated key keeps its first place
+func (o *jsonObject) set(key string, value any) {
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+// decodeJSON parses a single JSON document, keeping numbers as their
+// source tokens and objects as jsonObjects. It keeps its own stack, as
+// buildChildren does, so deep documents cannot exhaust the goroutine's.
+func decodeJSON(data []byte) (any, error) {
+	type container struct {
+		object *jsonObject // nil for an array
+		array  []any
+		key    string
+		hasKey bool
+	}
+	var stack []*container
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return nil, err
+		}
+		top := (*container)(nil)
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+		}
+		if top != nil && top.object != nil && !top.hasKey && tok != json.Delim('}') {
+			top.key, _ = tok.(string)
+			top.hasKey = true
+			continue
+		}
+
+		var value any
+		switch tok {
+		case json.Delim('{'):
+			stack = append(stack, &container{object: &jsonObject{values: make(map[string]any)}})
+			continue
+		case json.Delim('['):
+			stack = append(stack, &container{array: []any{}})
+			continue
+		case json.Delim('}'), json.Delim(']'):
+			stack = stack[:len(stack)-1]
+			if top.object != nil {
+				value = top.object
+			} else {
+				value = top.array
+			}
+		default:
+			value = tok
+		}
+
+		if len(stack) == 0 {
+			if _, err := dec.Token(); err != io.EOF {
+				return nil, errors.New("invalid JSON: unexpected data after top-level value")
+			}
+			return value, nil
+		}
+		if parent := stack[len(stack)-1]; parent.object != nil {
+			parent.object.set(parent.key, value)
+			parent.hasKey = false
+		} else {
+			parent.array = append(parent.array, value)
+		}
+	}
+}
+
+// encodeJSON serializes v followed by a newline, indented by indent if it
+// is not "", with object keys in document order. <, > and & are written as
+// they are rather than as \u003c and the like, so saving an edit leaves
+// the file's other strings alone.
+func encodeJSON(v any, indent string) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, v, indent, "\n"); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// writeJSON writes v to buf, starting each line inside a container with
+// newline, the line break and indentation of the level v is at
+func writeJSON(buf *bytes.Buffer, v any, indent, newline string) error {
+	open, close, count := "", "", 0
+	switch v := v.(type) {
+	case *jsonObject:
+		open, close, count = "{", "}", len(v.keys)
+	case []any:
+		open, close, count = "[", "]", len(v)
+	default:
+		return writeScalar(buf, v)
+	}
+	buf.WriteString(open)
+	if count == 0 {
+		buf.WriteString(close)
+		return nil
+	}
+	inner, space := newline+indent, " "
+	if indent == "" {
+		inner, space = "", ""
+	}
+	for i := range count {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(inner)
+		member := any(nil)
+		switch v := v.(type) {
+		case *jsonObject:
+			if err := writeScalar(buf, v.keys[i]); err != nil {
+				return err
+			}
+			buf.WriteString(":" + space)
+			member = v.values[v.keys[i]]
+		case []any:
+			member = v[i]
+		}
+		if err := writeJSON(buf, member, indent, newline+indent); err != nil {
+			return err
+		}
+	}
+	if indent != "" {
+		buf.WriteString(newline)
+	}
+	buf.WriteString(close)
+	return nil
+}
+
+// writeScalar writes a string, number, bool, null or JSON Lines member
+// without HTML escaping
+func writeScalar(buf *bytes.Buffer, v any) error {
+	var scalar bytes.Buffer
+	enc := json.NewEncoder(&scalar)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	buf.WriteString(strings.TrimSuffix(scalar.String(), "\n"))
+	return nil
+}
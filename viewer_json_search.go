@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// startSearch prompts for text to find in keys and scalar values
+func (j *JSONViewer) startSearch() tea.Cmd {
+	return askInput("/", j.query, func(query string) tea.Cmd {
+		j.query = query
+		j.matches = nil
+		j.match = -1
+		if query != "" {
+			j.nextMatch(0)
+		}
+		return nil
+	})
+}
+
+// nextMatch moves the cursor to the match after it (dir 1), before it
+// (dir -1) or, for a new search, at or after it (dir 0), wrapping around
+// at the ends, and opens the containers the match is in
+func (j *JSONViewer) nextMatch(dir int) {
+	if j.query == "" || j.root == nil {
+		return
+	}
+	j.ensureMatches()
+	if len(j.matches) == 0 {
+		j.status = fmt.Sprintf("No match for %q", j.query)
+		return
+	}
+	top := j.viewRoot()
+	visible := j.visibleNodes()
+	var here []int
+	if j.cursor < len(visible) {
+		here = indexPath(top, visible[j.cursor])
+	}
+	i := 0
+	switch dir {
+	case 0:
+		i, _ = slices.BinarySearchFunc(j.matches, here, slices.Compare)
+	case 1:
+		i, _ = slices.BinarySearchFunc(j.matches, here, slices.Compare)
+		if i < len(j.matches) && slices.Equal(j.matches[i], here) {
+			i++
+		}
+	case -1:
+		i, _ = slices.BinarySearchFunc(j.matches, here, slices.Compare)
+		i--
+	}
+	switch {
+	case i >= len(j.matches):
+		i = 0
+		j.status = "Search wrapped to the top"
+	case i < 0:
+		i = len(j.matches) - 1
+		j.status = "Search wrapped to the bottom"
+	}
+	j.match = i
+	node := j.reveal(top, j.matches[i])
+	j.cursor = j.indexOf(node)
+	j.ensureVisible()
+}
+
+// ensureMatches finds the matches again when the document, an edit or
+// zooming has made the last ones stale
+func (j *JSONViewer) ensureMatches() {
+	top := j.viewRoot()
+	if j.matches != nil && j.matchRoot == top {
+		return
+	}
+	j.matchRoot = top
+	j.matches = findMatches(nodeValue(top), j.query, j.caseMode.ignoreCase(j.query))
+	j.match = -1
+}
+
+// matchCount is the header's "match X of Y" while a search is active
+func (j *JSONViewer) matchCount() string {
+	if j.match < 0 || j.match >= len(j.matches) {
+		return ""
+	}
+	return fmt.Sprintf("  match %d of %d", j.match+1, len(j.matches))
+}
+
+// findMatches walks a decoded value in display order and returns where
+// query appears in an object key or a scalar value, each as the member
+// indices leading to it. Unparsed JSON Lines match on their text.
+func findMatches(value any, query string, fold bool) [][]int {
+	if fold {
+		query = strings.ToLower(query)
+	}
+	contains := func(s string) bool {
+		if fold {
+			s = strings.ToLower(s)
+		}
+		return strings.Contains(s, query)
+	}
+
+	type member struct {
+		key   string // object key, "" in arrays
+		value any
+		path  []int
+	}
+	matches := [][]int{}
+	stack := []member{{value: value}}
+	for len(stack) > 0 {
+		m := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if len(m.path) > 0 && (m.key != "" && contains(m.key) || contains(scalarText(m.value))) {
+			matches = append(matches, m.path)
+		}
+		// Pushed last to first, so they come off the stack in order
+		switch v := m.value.(type) {
+		case *jsonObject:
+			for i := len(v.keys) - 1; i >= 0; i-- {
+				stack = append(stack, member{v.keys[i], v.values[v.keys[i]], append(slices.Clip(m.path), i)})
+			}
+		case []any:
+			for i := len(v) - 1; i >= 0; i-- {
+				stack = append(stack, member{"", v[i], append(slices.Clip(m.path), i)})
+			}
+		}
+	}
+	return matches
+}
+
+// scalarText is a scalar value as searched, or "" for a container
+func scalarText(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return fmt.Sprint(v)
+	case nil:
+		return "null"
+	case *jsonLine:
+		return v.text
+	}
+	return ""
+}
+
+// indexPath is the member indices leading from top down to node
+func indexPath(top, node *JSONNode) []int {
+	var path []int
+	for n := node; n != top && n.Parent != nil; n = n.Parent {
+		lo, _ := pageBounds(n.Parent)
+		path = append(path, lo+slices.Index(n.Parent.Children, n))
+	}
+	slices.Reverse(path)
+	return path
+}
+
+// reveal follows path down from top, building and turning to the pages
+// of the containers on the way and expanding them, but not the node it
+// ends at, and returns that node
+func (j *JSONViewer) reveal(top *JSONNode, path []int) *JSONNode {
+	node := top
+	for _, i := range path {
+		j.buildDeferred(node)
+		if containerLen(node.Value) > jsonPageSize && i/jsonPageSize != node.Page {
+			j.setPage(node, i/jsonPageSize)
+		}
+		lo, _ := pageBounds(node)
+		if i-lo < 0 || i-lo >= len(node.Children) {
+			break
+		}
+		node.Expanded = true
+		node = node.Children[i-lo]
+	}
+	return node
+}
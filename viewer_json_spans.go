@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// jsonSpan is where a value's source lies in the file, as byte offsets
+// from its first byte to just past its last
+type jsonSpan struct {
+	start, end int64
+}
+
+// JSONSpansMsg is sent when the source spans of a JSON file's values have
+// been found, for showing with b
+type JSONSpansMsg struct {
+	Path  string
+	Spans map[string]jsonSpan // by node path
+	Err   error
+}
+
+// toggleSpans shows or hides the cursor node's byte offsets in the
+// footer. They are found, by reading the file again as tokens, only while
+// shown.
+func (j *JSONViewer) toggleSpans() tea.Cmd {
+	j.showSpans = !j.showSpans
+	if !j.showSpans {
+		j.spans = nil
+		return nil
+	}
+	return j.loadSpans()
+}
+
+// loadSpans finds the spans for the file as it is now on disk
+func (j *JSONViewer) loadSpans() tea.Cmd {
+	j.spans = nil
+	path, lines := j.path, j.lines
+	return func() tea.Msg {
+		spans, err := readSpans(path, lines)
+		return JSONSpansMsg{Path: path, Spans: spans, Err: err}
+	}
+}
+
+// spanText is the footer's note of where node lies in the file
+func (j *JSONViewer) spanText(node *JSONNode) string {
+	if j.spans == nil {
+		return "Finding byte offsets..."
+	}
+	span, ok := j.spans[nodePath(node)]
+	if !ok {
+		return "No byte offsets for this node"
+	}
+	return fmt.Sprintf("Offset %d, %s (bytes %d–%d)", span.start, plural(int(span.end-span.start), "byte"), span.start, span.end)
+}
+
+// readSpans reads a JSON file, or each line of a JSON Lines file, for the
+// spans of its values
+func readSpans(path string, lines bool) (map[string]jsonSpan, error) {
+	content, err := readContent(path)
+	if err != nil {
+		return nil, err
+	}
+	spans := make(map[string]jsonSpan)
+	if !lines {
+		return spans, findSpans(content, "", 0, spans)
+	}
+	// Members are numbered as splitJSONLines numbers them, skipping
+	// blank lines; lines that do not parse have only their own span
+	var offset int64
+	member := 0
+	for _, line := range bytes.SplitAfter(content, []byte("\n")) {
+		text := bytes.TrimRight(line, "\r\n")
+		if len(bytes.TrimSpace(text)) > 0 {
+			prefix := fmt.Sprintf("[%d]", member)
+			if findSpans(text, prefix, offset, spans) != nil {
+				spans[prefix] = jsonSpan{offset, offset + int64(len(text))}
+			}
+			member++
+		}
+		offset += int64(len(line))
+	}
+	return spans, nil
+}
+
+// findSpans records the span of every value in a document by node path,
+// below prefix and shifted by base. It keeps its own stack, as buildChildren
+// does, so deep documents cannot exhaust the goroutine's.
+func findSpans(content []byte, prefix string, base int64, spans map[string]jsonSpan) error {
+	type container struct {
+		path    string
+		start   int64
+		array   bool
+		index   int    // members seen so far
+		key     string // key of the member being read
+		wantKey bool
+	}
+	var stack []*container
+
+	// Values start after the whitespace, colons and commas Token skips
+	start := func(offset int64) int64 {
+		for offset < int64(len(content)) && strings.IndexByte(" \t\r\n:,", content[offset]) >= 0 {
+			offset++
+		}
+		return offset
+	}
+	// path names the member being read, as nodePath would
+	path := func() string {
+		if len(stack) == 0 {
+			return prefix
+		}
+		top := stack[len(stack)-1]
+		key := top.key
+		if top.array {
+			key = fmt.Sprintf("[%d]", top.index)
+		}
+		if top.path == "" {
+			return key
+		}
+		return top.path + nodePathSep + key
+	}
+	// done moves the innermost container on past a member
+	done := func() {
+		if len(stack) == 0 {
+			return
+		}
+		top := stack[len(stack)-1]
+		top.index++
+		top.wantKey = !top.array
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(content))
+	dec.UseNumber()
+	for {
+		from := start(dec.InputOffset())
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		delim, isDelim := tok.(json.Delim)
+		if n := len(stack); n > 0 && stack[n-1].wantKey && delim != '}' {
+			stack[n-1].key, _ = tok.(string)
+			stack[n-1].wantKey = false
+			continue
+		}
+		switch {
+		case isDelim && (delim == '}' || delim == ']'):
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			spans[top.path] = jsonSpan{base + top.start, base + dec.InputOffset()}
+			done()
+		case isDelim:
+			stack = append(stack, &container{path: path(), start: from, array: delim == '[', wantKey: delim == '{'})
+		default:
+			spans[path()] = jsonSpan{base + from, base + dec.InputOffset()}
+			done()
+		}
+	}
+}
+
+// seekPath is the member indices down to the value a 1-based line of a
+// JSON file shows: the first to start on the line or, if none does, the
+// innermost one holding it. Lines past the end are taken as the last.
+func seekPath(content []byte, data any, line int) []int {
+	spans := make(map[string]jsonSpan)
+	if findSpans(content, "", 0, spans) != nil {
+		return nil
+	}
+	var lineStart int64
+	for range line - 1 {
+		i := bytes.IndexByte(content[lineStart:], '\n')
+		if i < 0 {
+			break
+		}
+		lineStart += int64(i) + 1
+	}
+	lineEnd := int64(len(content))
+	if i := bytes.IndexByte(content[lineStart:], '\n'); i >= 0 {
+		lineEnd = lineStart + int64(i)
+	}
+
+	best, bestSpan, startsOnLine := "", jsonSpan{-1, -1}, false
+	for path, span := range spans {
+		onLine := span.start >= lineStart && span.start < lineEnd
+		holds := span.start <= lineStart && span.end > lineStart
+		switch {
+		case onLine && (!startsOnLine || span.start < bestSpan.start):
+			best, bestSpan, startsOnLine = path, span, true
+		case holds && !startsOnLine && (bestSpan.start < 0 || span.end-span.start < bestSpan.end-bestSpan.start):
+			best, bestSpan = path, span
+		}
+	}
+
+	indices, value := []int{}, data
+	if best == "" {
+		return indices
+	}
+	for _, key := range strings.Split(best, nodePathSep) {
+		switch v := value.(type) {
+		case *jsonObject:
+			indices = append(indices, slices.Index(v.keys, key))
+			value = v.values[key]
+		case []any:
+			i, _ := strconv.Atoi(strings.Trim(key, "[]"))
+			indices = append(indices, i)
+			value = v[i]
+		}
+	}
+	return indices
+}
+
+// seekMember is the index of the JSON Lines member on a 1-based line, or
+// the last before it when the line is blank or past the end
+func seekMember(members []any, line int) []int {
+	for i := len(members) - 1; i >= 0; i-- {
+		if l, ok := members[i].(*jsonLine); ok && l.num <= line {
+			return []int{i}
+		}
+	}
+	return []int{}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// TestJSONDeepNesting loads and renders a document nested far deeper than
+// the tree is built on load, then walks down past that depth, which must
+// neither overflow the stack nor fail
+func TestJSONDeepNesting(t *testing.T) {
+	const depth = 10000 // encoding/json's own limit
+	var b strings.Builder
+	for i := range depth {
+		if i%2 == 0 {
+			b.WriteString(`{"a":`)
+		} else {
+			b.WriteString("[")
+		}
+	}
+	b.WriteString("1")
+	for i := depth - 1; i >= 0; i-- {
+		if i%2 == 0 {
+			b.WriteString("}")
+		} else {
+			b.WriteString("]")
+		}
+	}
+	path := filepath.Join(t.TempDir(), "deep.json")
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	j := NewJSONViewer(DefaultConfig().JSON)
+	j.SetSize(80, 24)
+	j.SetFocused(true)
+	settle(j, j.Load(path))
+	if j.err != nil {
+		t.Fatalf("load failed: %v", j.err)
+	}
+	if frame := j.View(); !strings.Contains(frame, "deep.json") {
+		t.Fatalf("frame has no header:\n%s", frame)
+	}
+
+	// Each step opens the node under the cursor and moves into it, going
+	// past the built levels into deferred ones
+	for range 3 * defaultJSONMaxDepth {
+		for _, key := range []string{"l", "j"} {
+			_, cmd := j.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+			settle(j, cmd)
+		}
+	}
+	visible := j.visibleNodes()
+	if got := len(visible); got < 3*defaultJSONMaxDepth {
+		t.Errorf("%d nodes visible after expanding %d levels", got, 3*defaultJSONMaxDepth)
+	}
+	j.View()
+}
+
+// TestJSONRoundTripKeepsKeys decodes and encodes a document, which must
+// come back as it was: keys in their order and <, > and & unescaped
+func TestJSONRoundTripKeepsKeys(t *testing.T) {
+	const doc = "{\n  \"z\": \"<a> & b\",\n  \"a\": {\n    \"y\": 1.50,\n    \"b\": [\n      true,\n      null,\n      {}\n    ]\n  },\n  \"m\": []\n}\n"
+	value, err := decodeJSON([]byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := encodeJSON(value, "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != doc {
+		t.Errorf("got\n%s\nwant\n%s", data, doc)
+	}
+}
+
+// TestJSONSeekLine opens a document at a line, which must put the cursor
+// on the value starting there, or the innermost one holding a line where
+// none starts
+func TestJSONSeekLine(t *testing.T) {
+	const doc = "{\n  \"z\": 1,\n  \"a\": {\n    \"y\": [\n      true\n    ]\n  }\n}\n"
+	path := filepath.Join(t.TempDir(), "seek.json")
+	if err := os.WriteFile(path, []byte(doc), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for line, want := range map[int]string{1: "", 2: "z", 4: "a\x00y", 5: "a\x00y\x00[0]", 6: "a\x00y", 99: ""} {
+		j := NewJSONViewer(DefaultConfig().JSON)
+		j.SetSize(80, 24)
+		j.SeekLine(line)
+		settle(j, j.Load(path))
+		if got := nodePath(j.visibleNodes()[j.cursor]); got != want {
+			t.Errorf("line %d: cursor on %q, want %q", line, got, want)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"time"
+)
+
+// Limits on the JSON views remembered across sessions: the most recently
+// left files are kept, views not used for a while are forgotten, and a
+// view with very many expanded nodes is not worth the file it takes.
+const (
+	jsonViewsMax        = 100
+	jsonViewMaxAge      = 30 * 24 * time.Hour
+	jsonViewMaxExpanded = 5000
+)
+
+// savedJSONView is a jsonViewState kept on disk for one file, valid while
+// the file's modification time is unchanged
+type savedJSONView struct {
+	ModTime  time.Time      `json:"mod_time"`
+	Used     time.Time      `json:"used"`
+	Expanded []string       `json:"expanded"`
+	Pages    map[string]int `json:"pages,omitempty"`
+	Focus    []string       `json:"focus,omitempty"`
+	Cursor   string         `json:"cursor"`
+}
+
+// SetSavedViews sets the views remembered from earlier sessions and the
+// file they are saved to, dropping those too old to restore
+func (j *JSONViewer) SetSavedViews(views map[string]savedJSONView, path string) {
+	if views == nil {
+		views = make(map[string]savedJSONView)
+	}
+	for file, view := range views {
+		if time.Since(view.Used) > jsonViewMaxAge {
+			delete(views, file)
+		}
+	}
+	j.savedViews = views
+	j.savedViewsPath = path
+}
+
+// savedView returns the remembered view of path if the file is unchanged
+// since it was left
+func (j *JSONViewer) savedView(path string) *jsonViewState {
+	view, ok := j.savedViews[path]
+	if !ok {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if err != nil || !info.ModTime().Equal(view.ModTime) {
+		delete(j.savedViews, path)
+		return nil
+	}
+	state := &jsonViewState{expanded: make(map[string]bool), pages: view.Pages, focus: view.Focus, cursor: view.Cursor}
+	for _, p := range view.Expanded {
+		state.expanded[p] = true
+	}
+	if state.pages == nil {
+		state.pages = make(map[string]int)
+	}
+	return state
+}
+
+// rememberView records the view of the loaded file, to be restored when it
+// is opened again unchanged
+func (j *JSONViewer) rememberView() {
+	if j.savedViews == nil || j.root == nil || j.modTime.IsZero() {
+		return
+	}
+	state := j.captureState()
+	if len(state.expanded) > jsonViewMaxExpanded {
+		delete(j.savedViews, j.path)
+		return
+	}
+	j.savedViews[j.path] = savedJSONView{
+		ModTime:  j.modTime,
+		Used:     time.Now(),
+		Expanded: slices.Sorted(maps.Keys(state.expanded)),
+		Pages:    state.pages,
+		Focus:    state.focus,
+		Cursor:   state.cursor,
+	}
+	// Forget the least recently used files past the limit
+	for len(j.savedViews) > jsonViewsMax {
+		oldest := ""
+		for file, view := range j.savedViews {
+			if oldest == "" || view.Used.Before(j.savedViews[oldest].Used) {
+				oldest = file
+			}
+		}
+		delete(j.savedViews, oldest)
+	}
+}
+
+// saveViews remembers the current view and writes the remembered views
+func (j *JSONViewer) saveViews() {
+	j.rememberView()
+	if j.savedViewsPath == "" {
+		return
+	}
+	if err := saveJSON(j.savedViewsPath, j.savedViews); err != nil {
+		log.Printf("saving JSON views: %v", err)
+	}
+}
+
+// Close saves the views, including the one being left on exit
+func (j *JSONViewer) Close() {
+	if j.root != nil {
+		j.saveViews()
+	}
+}
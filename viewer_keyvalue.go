@@ -0,0 +1,98 @@
+package main
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// secretKeyRe matches keys whose values are masked until revealed
+var secretKeyRe = regexp.MustCompile(`(?i)secret|passw(or)?d|token|(^|_)key$|api_?key|private|credential|auth`)
+
+// secretMask stands in for a masked value; it is the same for every
+// value, so the length of a secret is not given away either
+const secretMask = "••••••••"
+
+// KeyValueViewer shows .env and properties files as KEY=VALUE lines, with
+// keys and values colored apart, comments dimmed and values of secret
+// looking keys masked. It reads files like TextViewer, a window at a time.
+type KeyValueViewer struct {
+	*TextViewer
+	reveal bool // show secret values as written
+}
+
+func NewKeyValueViewer() *KeyValueViewer {
+	k := &KeyValueViewer{TextViewer: NewTextViewer(nil)}
+	k.styleLine = k.renderLine
+	return k
+}
+
+func (k *KeyValueViewer) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok && k.focused && !k.visual && msg.String() == "m" {
+		k.reveal = !k.reveal
+		k.status = "Secrets masked"
+		if k.reveal {
+			k.status = "Secrets shown"
+		}
+		return k, nil
+	}
+	_, cmd := k.TextViewer.Update(msg)
+	return k, cmd
+}
+
+// ClaimsKey keeps "m" for masking and showing secrets, besides the keys
+// the text viewer keeps
+func (k *KeyValueViewer) ClaimsKey(key string) bool {
+	return key == "m" || k.TextViewer.ClaimsKey(key)
+}
+
+func (k *KeyValueViewer) Name() string {
+	return "keyvalue"
+}
+
+// CanView takes .env files, with or without a suffix such as .env.local,
+// and Java-style .properties files
+func (k *KeyValueViewer) CanView(path string) bool {
+	name := strings.ToLower(filepath.Base(path))
+	return name == ".env" || strings.HasPrefix(name, ".env.") ||
+		strings.HasSuffix(name, ".env") || strings.HasSuffix(name, ".properties")
+}
+
+var (
+	kvKeyStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("81"))
+	kvValueStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("114"))
+	kvSecretStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("168"))
+	kvCommentStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
+)
+
+// renderLine styles one line. Lines that are neither comments nor
+// assignments are left as they are.
+func (k *KeyValueViewer) renderLine(line string) string {
+	body := strings.TrimLeft(line, " \t")
+	indent := line[:len(line)-len(body)]
+	if strings.HasPrefix(body, "#") || strings.HasPrefix(body, "!") {
+		return indent + kvCommentStyle.Render(body)
+	}
+
+	export := ""
+	if rest, ok := strings.CutPrefix(body, "export "); ok {
+		export, body = "export ", rest
+	}
+	key, value, ok := strings.Cut(body, "=")
+	if !ok || strings.TrimSpace(key) == "" {
+		return line
+	}
+
+	valueStyle := kvValueStyle
+	if secretKeyRe.MatchString(strings.TrimSpace(key)) && strings.TrimSpace(value) != "" {
+		valueStyle = kvSecretStyle
+		if !k.reveal {
+			value = secretMask
+		}
+	}
+	return indent + kvCommentStyle.Render(export) + kvKeyStyle.Render(key) +
+		kvCommentStyle.Render("=") + valueStyle.Render(value)
+}
@@ -1,30 +1,64 @@
 package main
 
 import (
+	"fmt"
+	"log"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
+	"time"
+	"unicode"
 
-	"github.com/charmbracelet/glamour"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/glamour/styles"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
 )
 
-// MarkdownViewer renders markdown files with glamour
+// markdownMaxWrap caps the wrap width so prose stays readable when the
+// pane is very wide
+const markdownMaxWrap = 80
+
+// MarkdownViewer renders markdown files with glamour, or shows the source
+// as plain text in plain mode
 type MarkdownViewer struct {
 	width   int
 	height  int
 	focused bool
+	plain   bool // show the source line for line instead of rendering it
+
+	scrollOff   int
+	cursorStyle cursorStyle
+	scrollbar   scrollbarStyle
+
+	path          string
+	source        string // markdown source, kept to re-render on resize
+	modTime       time.Time
+	cache         *renderCache // glamour renderings by file and width
+	rendered      string
+	renderedWidth int // wrap width the current rendering used
+	lines         []string
+	offset        int
+	err           error
+	renderErr     error // glamour failed and the source is shown as plain text
+
+	headings  []mdHeading
+	tocOpen   bool // table of contents shown instead of the document
+	tocCursor int
+	tocOffset int
 
-	path     string
-	rendered string
-	lines    []string
-	offset   int
-	err      error
+	links      []mdLink
+	linkCursor int    // selected link, -1 when none
+	status     string // result of the last link action
+
+	seekLine int // 1-based source line the next load scrolls to
 }
 
-func NewMarkdownViewer() *MarkdownViewer {
-	return &MarkdownViewer{}
+func NewMarkdownViewer(cfg MarkdownConfig) *MarkdownViewer {
+	return &MarkdownViewer{plain: cfg.Plain, cache: newRenderCache(cfg.CacheSize), cursorStyle: defaultCursor, scrollbar: defaultScrollbar}
 }
 
 func (m *MarkdownViewer) Init() tea.Cmd {
@@ -34,18 +68,76 @@ func (m *MarkdownViewer) Init() tea.Cmd {
 func (m *MarkdownViewer) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case MarkdownLoadedMsg:
-		if msg.Path == m.path {
+		if msg.Path == m.path && msg.Plain == m.plain {
 			m.rendered = msg.Content
 			m.lines = strings.Split(msg.Content, "\n")
-			m.offset = 0
+			m.source = msg.Source
+			m.modTime = msg.ModTime
+			m.renderedWidth = msg.Width
 			m.err = msg.Err
+			m.renderErr = msg.RenderErr
+			m.headings = msg.Headings
+			m.links = msg.Links
+			if msg.Rerender {
+				// Same document at a new width: keep the reader's place
+				m.scroll(0)
+				if m.linkCursor >= len(m.links) {
+					m.linkCursor = -1
+				}
+				break
+			}
+			m.offset = 0
+			m.tocOpen = false
+			m.tocCursor = 0
+			m.tocOffset = 0
+			m.linkCursor = -1
+			m.status = ""
+			if m.seekLine > 0 {
+				m.seek(m.seekLine)
+				m.seekLine = 0
+			}
 		}
 
+	case ClipboardMsg:
+		m.status = msg.Status()
+
+	case ExternalOpenedMsg:
+		m.status = msg.Status()
+
 	case tea.KeyMsg:
 		if !m.focused {
 			return m, nil
 		}
+		if m.tocOpen {
+			m.updateTOC(msg)
+			return m, nil
+		}
+		m.status = ""
 		switch msg.String() {
+		case "]":
+			m.selectLink(1)
+		case "[":
+			m.selectLink(-1)
+		case "enter":
+			return m, m.followLink()
+		case "esc":
+			m.linkCursor = -1
+		case "t":
+			if len(m.headings) > 0 {
+				m.tocOpen = true
+			}
+		case "Y":
+			return m, yankFile(m.path)
+		case "y", "ctrl+y":
+			// The rendering as plain text, or with its colors for pasting
+			// into a terminal
+			if m.source != "" && m.err == nil {
+				what := "rendered " + filepath.Base(m.path)
+				if msg.String() == "ctrl+y" {
+					return m, copyToClipboard(m.renderedText(true), what+" with colors")
+				}
+				return m, copyToClipboard(m.renderedText(false), what)
+			}
 		case "j", "down":
 			m.scroll(1)
 		case "k", "up":
@@ -69,7 +161,10 @@ func (m *MarkdownViewer) View() string {
 		return m.centerText("Select a markdown file to view")
 	}
 	if m.err != nil {
-		return m.centerText("Error: " + m.err.Error())
+		return m.centerText(loadErrorText(m.err))
+	}
+	if m.tocOpen {
+		return m.viewTOC()
 	}
 
 	var visible []string
@@ -78,15 +173,35 @@ func (m *MarkdownViewer) View() string {
 		end = len(m.lines)
 	}
 
+	var selectedLine = -1
+	if m.linkCursor >= 0 {
+		selectedLine = m.links[m.linkCursor].Line
+	}
+	marker := lipgloss.NewStyle().Foreground(lipgloss.Color("214")).Bold(true).Render("›")
 	for i := m.offset; i < end; i++ {
-		visible = append(visible, m.lines[i])
+		line := m.lines[i]
+		if m.showingPlain() {
+			// Source lines are not wrapped, so clip them like TextViewer
+			line = ansi.Truncate(line, max(0, m.width-1), "…")
+		}
+		if i == selectedLine {
+			// Mark the selected link's line in the left margin
+			line = marker + ansi.TruncateLeft(line, 1, "")
+		}
+		visible = append(visible, line)
 	}
 
 	// Header with filename
+	title := filepath.Base(m.path) + compressedNote(m.path)
+	if m.plain {
+		title += " [plain]"
+	} else if m.renderErr != nil {
+		title += " [plain: rendering failed]"
+	}
 	header := lipgloss.NewStyle().
 		Bold(true).
 		Foreground(lipgloss.Color("12")).
-		Render(filepath.Base(m.path))
+		Render(title)
 
 	lines := append([]string{header}, visible...)
 
@@ -94,10 +209,89 @@ func (m *MarkdownViewer) View() string {
 	for len(lines) < m.height {
 		lines = append(lines, "")
 	}
+	m.scrollbar.draw(lines, 1, m.height-2, m.width, m.offset, len(m.lines))
+
+	// Link and status messages take over the last line
+	if footer := m.footer(); footer != "" && len(lines) > 1 {
+		lines[len(lines)-1] = footer
+	}
 
 	return strings.Join(lines, "\n")
 }
 
+func (m *MarkdownViewer) footer() string {
+	style := lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
+	switch {
+	case m.status != "":
+		return style.Render(ansi.Truncate(m.status, m.width, "…"))
+	case m.renderErr != nil && m.linkCursor < 0:
+		text := "Rich rendering failed (" + m.renderErr.Error() + "); details in " + logName
+		return style.Render(ansi.Truncate(text, m.width, "…"))
+	case m.linkCursor >= 0:
+		link := m.links[m.linkCursor]
+		text := fmt.Sprintf("Link %d/%d: %s (enter to open)", m.linkCursor+1, len(m.links), link.Target)
+		return style.Render(ansi.Truncate(text, m.width, "…"))
+	}
+	return ""
+}
+
+// selectLink moves to the next or previous link, scrolling it into view
+func (m *MarkdownViewer) selectLink(delta int) {
+	if len(m.links) == 0 {
+		return
+	}
+	if m.linkCursor < 0 && delta < 0 {
+		m.linkCursor = len(m.links) - 1
+	} else {
+		m.linkCursor = (m.linkCursor + delta + len(m.links)) % len(m.links)
+	}
+	line := m.links[m.linkCursor].Line
+	if line >= 0 && (line < m.offset || line >= m.offset+m.height-2) {
+		m.offset = 0
+		m.scroll(line - m.height/2)
+	}
+}
+
+// followLink acts on the selected link: anchors scroll to their heading,
+// web links open in the browser after confirmation, and anything else is
+// opened as a file relative to the document
+func (m *MarkdownViewer) followLink() tea.Cmd {
+	if m.linkCursor < 0 {
+		return nil
+	}
+	target := m.links[m.linkCursor].Target
+
+	switch {
+	case strings.HasPrefix(target, "#"):
+		for _, h := range m.headings {
+			if headingSlug(h.Title) == strings.ToLower(target[1:]) && h.Line >= 0 {
+				m.offset = 0
+				m.scroll(h.Line)
+				return nil
+			}
+		}
+		m.status = "No heading for " + target
+		return nil
+
+	case strings.HasPrefix(target, "http://"), strings.HasPrefix(target, "https://"), strings.HasPrefix(target, "mailto:"):
+		return askConfirm("open_link", "Open "+target+" in browser?", func() tea.Cmd {
+			return openExternal(target)
+		}, nil)
+	}
+
+	file, _, _ := strings.Cut(target, "#")
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(filepath.Dir(m.path), file)
+	}
+	if _, err := os.Stat(file); err != nil {
+		m.status = "Cannot open " + target + ": " + errorText(err)
+		return nil
+	}
+	return func() tea.Msg {
+		return FileSelectedMsg{Path: file}
+	}
+}
+
 func (m *MarkdownViewer) SetSize(width, height int) {
 	m.width = width
 	m.height = height
@@ -111,6 +305,19 @@ func (m *MarkdownViewer) SetFocused(focused bool) {
 	m.focused = focused
 }
 
+// FailedLoad returns the file whose load failed and why, or "" and nil
+// when it did not
+func (m *MarkdownViewer) FailedLoad() (string, error) {
+	if m.err == nil {
+		return "", nil
+	}
+	return m.path, m.err
+}
+
+func (m *MarkdownViewer) Name() string {
+	return "markdown"
+}
+
 func (m *MarkdownViewer) CanView(path string) bool {
 	ext := strings.ToLower(filepath.Ext(path))
 	return ext == ".md" || ext == ".markdown"
@@ -118,28 +325,473 @@ func (m *MarkdownViewer) CanView(path string) bool {
 
 func (m *MarkdownViewer) Load(path string) tea.Cmd {
 	m.path = path
+	m.source = ""
+	width, plain, cache := m.wrapWidth(), m.plain, m.cache
 	return func() tea.Msg {
-		content, err := os.ReadFile(path)
+		if err := checkSpecial(path); err != nil {
+			return MarkdownLoadedMsg{Path: path, Plain: plain, Err: err}
+		}
+		var modTime time.Time
+		if info, err := os.Stat(path); err == nil {
+			modTime = info.ModTime()
+		}
+		content, err := readContent(path)
 		if err != nil {
-			return MarkdownLoadedMsg{Path: path, Err: err}
+			return MarkdownLoadedMsg{Path: path, Plain: plain, Err: err}
 		}
+		msg := renderMarkdown(path, string(content), width, plain, false, cache.glamour(path, modTime))
+		msg.ModTime = modTime
+		return msg
+	}
+}
 
-		// Render markdown with glamour
-		renderer, err := glamour.NewTermRenderer(
-			glamour.WithAutoStyle(),
-			glamour.WithWordWrap(80),
-		)
-		if err != nil {
-			return MarkdownLoadedMsg{Path: path, Err: err}
+// Rerender re-renders the loaded document if the wrap width has changed
+// since it was last rendered, and does nothing otherwise. Plain mode does
+// not wrap, so it never needs re-rendering.
+func (m *MarkdownViewer) Rerender() tea.Cmd {
+	width := m.wrapWidth()
+	if m.source == "" || m.err != nil || m.plain || width == m.renderedWidth {
+		return nil
+	}
+	m.renderedWidth = width // don't queue the same render twice
+	path, source, modTime := m.path, m.source, m.modTime
+	render := m.cache.glamour(path, modTime)
+	return func() tea.Msg {
+		msg := renderMarkdown(path, source, width, false, true, render)
+		msg.ModTime = modTime
+		return msg
+	}
+}
+
+// togglePlain switches between rich and plain mode, re-rendering the
+// loaded document in place
+func (m *MarkdownViewer) togglePlain() tea.Cmd {
+	m.plain = !m.plain
+	m.linkCursor = -1
+	if m.source == "" || m.err != nil {
+		return nil
+	}
+	width, plain := m.wrapWidth(), m.plain
+	m.renderedWidth = width
+	path, source, modTime := m.path, m.source, m.modTime
+	render := m.cache.glamour(path, modTime)
+	return func() tea.Msg {
+		msg := renderMarkdown(path, source, width, plain, true, render)
+		msg.ModTime = modTime
+		return msg
+	}
+}
+
+// renderedText is the document as shown, without the blank lines and
+// padding around it, and with its ANSI styling only if keepANSI is set
+func (m *MarkdownViewer) renderedText(keepANSI bool) string {
+	lines := make([]string, len(m.lines))
+	for i, line := range m.lines {
+		if !keepANSI {
+			line = ansi.Strip(line)
 		}
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	return strings.Trim(strings.Join(lines, "\n"), "\n") + "\n"
+}
 
-		rendered, err := renderer.Render(string(content))
-		if err != nil {
-			return MarkdownLoadedMsg{Path: path, Err: err}
+// ToggleFormat switches between the rendering and plain mode
+func (m *MarkdownViewer) ToggleFormat() tea.Cmd {
+	return m.togglePlain()
+}
+
+// showingPlain reports whether the content is the plain rendering, chosen
+// or as a fallback
+func (m *MarkdownViewer) showingPlain() bool {
+	return m.plain || m.renderErr != nil
+}
+
+// wrapWidth is the column glamour wraps at: the pane width, capped to keep
+// lines readable on wide terminals
+func (m *MarkdownViewer) wrapWidth() int {
+	return max(20, min(m.width-2, markdownMaxWrap))
+}
+
+// renderMarkdown renders source with glamour, or as plain text, and
+// locates its headings and links in the output. If glamour fails the
+// source is shown as plain text instead and the error is logged.
+func renderMarkdown(path, source string, width int, plain, rerender bool, render func(string, int) (string, error)) MarkdownLoadedMsg {
+	var rendered string
+	var renderErr error
+	if !plain {
+		rendered, renderErr = render(source, width)
+		if renderErr != nil {
+			log.Printf("markdown: rendering %s: %v", path, renderErr)
 		}
+	}
+	if plain || renderErr != nil {
+		rendered = renderPlain(source)
+	}
+
+	renderedLines := strings.Split(rendered, "\n")
+	headings := parseHeadings(source)
+	locateHeadings(headings, renderedLines)
+	links := parseLinks(source)
+	locateLinks(links, renderedLines)
 
-		return MarkdownLoadedMsg{Path: path, Content: rendered}
+	return MarkdownLoadedMsg{
+		Path:      path,
+		Source:    source,
+		Content:   rendered,
+		Width:     width,
+		Headings:  headings,
+		Links:     links,
+		Plain:     plain,
+		Rerender:  rerender,
+		RenderErr: renderErr,
+	}
+}
+
+// glamourStyle picks glamour's style; tests fix it so their frames do not
+// depend on the terminal
+var glamourStyle = styles.AutoStyle
+
+func renderGlamour(source string, width int) (string, error) {
+	renderer, err := glamour.NewTermRenderer(
+		glamour.WithStandardStyle(glamourStyle),
+		glamour.WithWordWrap(width),
+	)
+	if err != nil {
+		return "", err
+	}
+	return renderer.Render(source)
+}
+
+// renderPlain returns the source line for line, only making ATX headings
+// bold so the structure stays visible. Text is otherwise untouched so it
+// copies out of the terminal exactly as written.
+func renderPlain(source string) string {
+	headingStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("12"))
+	lines := strings.Split(strings.ReplaceAll(source, "\r\n", "\n"), "\n")
+	fence := ""
+	for i, line := range lines {
+		line = strings.ReplaceAll(line, "\t", "    ")
+		lines[i] = line
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fence = trimmed[:3]
+			continue
+		}
+		if atxLevel(trimmed) > 0 {
+			lines[i] = headingStyle.Render(line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (m *MarkdownViewer) updateTOC(msg tea.KeyMsg) {
+	switch msg.String() {
+	case "j", "down":
+		m.tocCursor = min(m.tocCursor+1, len(m.headings)-1)
+	case "k", "up":
+		m.tocCursor = max(m.tocCursor-1, 0)
+	case "g":
+		m.tocCursor = 0
+	case "G":
+		m.tocCursor = len(m.headings) - 1
+	case "enter", "l", "right":
+		if line := m.headings[m.tocCursor].Line; line >= 0 {
+			m.tocOpen = false
+			m.offset = 0
+			m.scroll(line)
+		}
+	case "t", "esc", "q":
+		m.tocOpen = false
+	}
+
+	m.tocOffset = keepInView(m.tocCursor, m.tocOffset, max(1, m.height-1), len(m.headings), m.scrollOff)
+}
+
+// SetScrollOff sets the rows of context kept around the contents cursor
+func (m *MarkdownViewer) SetScrollOff(lines int) {
+	m.scrollOff = lines
+}
+
+// SetScrollbar sets how the scrollbar is drawn
+func (m *MarkdownViewer) SetScrollbar(style scrollbarStyle) {
+	m.scrollbar = style
+}
+
+// SetCursorStyle sets how the contents entry under the cursor is drawn
+func (m *MarkdownViewer) SetCursorStyle(style cursorStyle) {
+	m.cursorStyle = style
+}
+
+func (m *MarkdownViewer) viewTOC() string {
+	header := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("12")).
+		Render(filepath.Base(m.path) + " — Contents")
+	lines := []string{header}
+
+	missingStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
+	end := min(m.tocOffset+m.height-1, len(m.headings))
+	for i := m.tocOffset; i < end; i++ {
+		h := m.headings[i]
+		line := strings.Repeat("  ", h.Level-1) + h.Title
+		line = ansi.Truncate(line, max(0, m.width-1), "…")
+		if h.Line < 0 {
+			line = missingStyle.Render(line)
+		}
+		if i == m.tocCursor {
+			line = m.cursorStyle.render(line, m.width)
+		}
+		lines = append(lines, line)
+	}
+
+	for len(lines) < m.height {
+		lines = append(lines, "")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// mdHeading is a heading from the markdown source and the line of the
+// rendered output it appears on
+type mdHeading struct {
+	Level  int
+	Title  string
+	Source int // 0-based line in the source
+	Line   int // -1 if it could not be found in the rendered output
+}
+
+// parseHeadings extracts ATX ("## Title") and Setext (underlined) headings,
+// ignoring fenced code blocks
+func parseHeadings(src string) []mdHeading {
+	var headings []mdHeading
+	lines := strings.Split(src, "\n")
+	fence := ""
+	for i, raw := range lines {
+		line := strings.TrimRight(raw, "\r")
+		trimmed := strings.TrimSpace(line)
+
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fence = trimmed[:3]
+			continue
+		}
+
+		if level := atxLevel(trimmed); level > 0 {
+			title := strings.TrimSpace(strings.TrimLeft(trimmed, "#"))
+			title = strings.TrimSpace(strings.TrimRight(title, "#"))
+			headings = append(headings, mdHeading{Level: level, Title: plainInline(title), Source: i, Line: -1})
+			continue
+		}
+
+		// Setext: a non-blank line underlined with = (h1) or - (h2)
+		if i+1 < len(lines) && trimmed != "" {
+			under := strings.TrimSpace(lines[i+1])
+			level := 0
+			if under != "" && strings.Trim(under, "=") == "" {
+				level = 1
+			} else if len(under) >= 2 && strings.Trim(under, "-") == "" {
+				level = 2
+			}
+			if level > 0 && !strings.HasPrefix(trimmed, "- ") {
+				headings = append(headings, mdHeading{Level: level, Title: plainInline(trimmed), Source: i, Line: -1})
+			}
+		}
+	}
+	return headings
+}
+
+// atxLevel returns the level of an ATX heading line, or 0
+func atxLevel(line string) int {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 {
+		return 0
+	}
+	if level < len(line) && line[level] != ' ' && line[level] != '\t' {
+		return 0
+	}
+	return level
+}
+
+// plainInline drops inline markup from a heading title: emphasis and code
+// markers, and link targets
+func plainInline(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch c {
+		case '*', '_', '`':
+			continue
+		case ']':
+			// Skip a "(target)" right after link text
+			if i+1 < len(s) && s[i+1] == '(' {
+				if end := strings.IndexByte(s[i:], ')'); end >= 0 {
+					i += end
+				}
+			}
+			continue
+		case '[':
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// locateHeadings finds each heading's line in the rendered output. Headings
+// are searched in order, each starting after the previous match, comparing
+// only letters and digits since the renderer restyles everything else.
+func locateHeadings(headings []mdHeading, rendered []string) {
+	start := 0
+	for i := range headings {
+		want := headingKey(headings[i].Title)
+		if want == "" {
+			continue
+		}
+		for l := start; l < len(rendered); l++ {
+			got := headingKey(ansi.Strip(rendered[l]))
+			// Long titles may wrap, leaving only a prefix on this line
+			if got != "" && (strings.HasPrefix(got, want) || (len(got) >= 8 && strings.HasPrefix(want, got))) {
+				headings[i].Line = l
+				start = l + 1
+				break
+			}
+		}
+	}
+}
+
+// mdLink is a link from the markdown source and the rendered line its
+// text appears on
+type mdLink struct {
+	Text   string
+	Target string
+	Line   int // -1 if it could not be found in the rendered output
+}
+
+var (
+	inlineLinkRe = regexp.MustCompile(`!?\[([^\]]*)\]\(\s*<?([^)\s>]+)>?(?:\s+"[^"]*")?\s*\)`)
+	autoLinkRe   = regexp.MustCompile(`<((?:https?://|mailto:)[^>\s]+)>`)
+	codeSpanRe   = regexp.MustCompile("`[^`]*`")
+)
+
+// parseLinks extracts inline [text](target) and <url> links in document
+// order, skipping code blocks and code spans
+func parseLinks(src string) []mdLink {
+	var links []mdLink
+	fence := ""
+	for _, raw := range strings.Split(src, "\n") {
+		trimmed := strings.TrimSpace(raw)
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fence = trimmed[:3]
+			continue
+		}
+
+		line := codeSpanRe.ReplaceAllString(raw, "")
+		type found struct {
+			at   int
+			link mdLink
+		}
+		var inLine []found
+		for _, m := range inlineLinkRe.FindAllStringSubmatchIndex(line, -1) {
+			inLine = append(inLine, found{m[0], mdLink{Text: line[m[2]:m[3]], Target: line[m[4]:m[5]], Line: -1}})
+		}
+		for _, m := range autoLinkRe.FindAllStringSubmatchIndex(line, -1) {
+			url := line[m[2]:m[3]]
+			inLine = append(inLine, found{m[0], mdLink{Text: url, Target: url, Line: -1}})
+		}
+		// Keep left-to-right order when both kinds share a line
+		sort.Slice(inLine, func(i, j int) bool { return inLine[i].at < inLine[j].at })
+		for _, f := range inLine {
+			links = append(links, f.link)
+		}
+	}
+	return links
+}
+
+// locateLinks finds the rendered line holding each link's text, searching
+// in document order like locateHeadings
+func locateLinks(links []mdLink, rendered []string) {
+	start := 0
+	for i := range links {
+		want := headingKey(plainInline(links[i].Text))
+		if want == "" {
+			want = headingKey(links[i].Target)
+		}
+		for l := start; l < len(rendered); l++ {
+			if strings.Contains(headingKey(ansi.Strip(rendered[l])), want) {
+				links[i].Line = l
+				start = l // several links can share a line
+				break
+			}
+		}
+	}
+}
+
+// headingSlug converts a heading title to its anchor the way GitHub does:
+// lowercase, punctuation dropped, spaces as hyphens
+func headingSlug(title string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(title) {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r), r == '-', r == '_':
+			b.WriteRune(r)
+		case r == ' ':
+			b.WriteRune('-')
+		}
+	}
+	return b.String()
+}
+
+// headingKey reduces text to lowercase words of letters and digits
+func headingKey(s string) string {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, " ")
+}
+
+// SeekLine sets the 1-based source line the next loaded file is scrolled
+// to
+func (m *MarkdownViewer) SeekLine(line int) {
+	m.seekLine = line
+}
+
+// seek scrolls to a 1-based source line: to the line itself in plain
+// mode, which shows the source line for line, and otherwise to the
+// section it is in, since the rendering does not keep the source's lines
+func (m *MarkdownViewer) seek(line int) {
+	m.offset = 0
+	if m.showingPlain() {
+		m.scroll(line - 1 - m.height/4)
+		return
+	}
+	for _, h := range m.headings {
+		if h.Source >= line {
+			break
+		}
+		if h.Line >= 0 {
+			m.offset = h.Line
+		}
 	}
+	m.scroll(0)
 }
 
 func (m *MarkdownViewer) scroll(delta int) {
@@ -166,7 +818,17 @@ func (m *MarkdownViewer) centerText(text string) string {
 
 // MarkdownLoadedMsg is sent when markdown has been rendered
 type MarkdownLoadedMsg struct {
-	Path    string
-	Content string
-	Err     error
+	Path     string
+	Source   string
+	Content  string
+	Width    int // wrap width used
+	Headings []mdHeading
+	Links    []mdLink
+	Plain    bool      // rendered in plain mode
+	Rerender bool      // same source rendered again at a new width or mode
+	ModTime  time.Time // of the file as read, zero if unknown
+	Err      error
+	// RenderErr is set when glamour failed and Content is the plain
+	// rendering instead
+	RenderErr error
 }
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestTextViewBeforeLoad draws the text viewer between starting the first
+// load and it finishing, when there is no line index yet
+func TestTextViewBeforeLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.txt")
+	if err := os.WriteFile(path, []byte("one\ntwo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	v := NewTextViewer(nil)
+	v.SetSize(40, 10)
+	v.SetFormatBadge(newFormatBadge(DefaultConfig().Encoding))
+	cmd := v.Load(path)
+	v.View()
+	settle(v, cmd)
+	v.View()
+}